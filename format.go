@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"ljpprojects.org/sqopl/lexer"
+)
+
+// Formats each file, printing the result or, with -w, writing it back to the file.
+func runFmt(args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to each file instead of printing it")
+
+	sources, err := parseCommandLine(flags, args)
+
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, source := range sources {
+		formatted, err := formatSource(source)
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Standard input has nowhere to be written back to
+		if *write && source.Name() != stdinName {
+			if formatted == string(source.Bytes()) {
+				continue
+			}
+
+			if err := os.WriteFile(source.Name(), []byte(formatted), 0o644); err != nil {
+				errs = append(errs, ioError{err})
+			}
+
+			continue
+		}
+
+		if _, err := fmt.Print(formatted); err != nil {
+			errs = append(errs, ioError{err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Normalises the whitespace between the tokens and comments of source, leaving everything else as it is.
+// Trailing whitespace is removed from every line, runs of blank lines are collapsed into one,
+// line breaks become "\n", and the file ends with exactly one line break.
+// Whitespace inside tokens, such as in multiline strings, is never changed.
+func formatSource(source *lexer.Source) (string, error) {
+	options := lexer.DefaultLexerOptions()
+	options.KeepComments = true
+	options.KeepWhitespace = true
+
+	lex := lexer.NewLexerWithOptions(source, options)
+	tokens, err := lex.CollectTokens()
+
+	if err != nil {
+		return "", sourceError{source.Name(), err}
+	}
+
+	var (
+		sb strings.Builder
+		// Whitespace is held back until the text after it is known
+		whitespace string
+	)
+
+	writeText := func(text string) {
+		sb.WriteString(normaliseWhitespace(whitespace, sb.Len() == 0))
+		sb.WriteString(text)
+		whitespace = ""
+	}
+
+	writeTrivia := func(trivia []lexer.Trivia) {
+		for _, t := range trivia {
+			if t.Kind() == lexer.WhitespaceTriviaKind {
+				whitespace += t.Characters()
+			} else {
+				writeText(strings.TrimRight(t.Characters(), " \t"))
+			}
+		}
+	}
+
+	for _, tk := range tokens {
+		writeTrivia(tk.LeadingTrivia())
+		writeText(tk.Characters())
+		writeTrivia(tk.TrailingTrivia())
+	}
+
+	writeTrivia(lex.EndOfFileTrivia())
+
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+
+	return sb.String(), nil
+}
+
+// Normalises whitespace that comes before some text. Whitespace at the start of the file is removed, apart from indentation.
+func normaliseWhitespace(whitespace string, atStart bool) string {
+	lines := strings.Split(strings.ReplaceAll(whitespace, "\r", ""), "\n")
+	indent := lines[len(lines)-1]
+
+	if atStart || len(lines) == 1 {
+		return indent
+	}
+
+	// At most one blank line, which is two line breaks
+	return strings.Repeat("\n", min(len(lines)-1, 2)) + indent
+}
@@ -3,3 +3,5 @@ module ljpprojects.org/sqopl
 go 1.24
 
 toolchain go1.24.5
+
+require golang.org/x/text v0.25.0
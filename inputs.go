@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ljpprojects.org/sqopl/lexer"
+)
+
+// The name given to source read from standard input.
+const stdinName = "<stdin>"
+
+// Reads the sources named by the file arguments of a command, in order.
+// "-" reads standard input, and arguments containing glob metacharacters are expanded with filepath.Glob,
+// for shells that leave unmatched or quoted patterns alone.
+func readInputs(args []string) ([]*lexer.Source, error) {
+	if len(args) == 0 {
+		return nil, usageError{"no input files, use - to read from standard input"}
+	}
+
+	var (
+		sources   []*lexer.Source
+		readStdin bool
+	)
+
+	for _, arg := range args {
+		if arg == "-" {
+			if readStdin {
+				return nil, usageError{"standard input can only be read once"}
+			}
+
+			readStdin = true
+
+			source, err := lexer.NewSourceFromReader(stdinName, os.Stdin)
+
+			if err != nil {
+				return nil, ioError{err}
+			}
+
+			sources = append(sources, source)
+
+			continue
+		}
+
+		paths := []string{arg}
+
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+
+			if err != nil {
+				return nil, usageError{fmt.Sprintf("invalid pattern %q: %s", arg, err)}
+			}
+
+			if len(matches) == 0 {
+				return nil, usageError{fmt.Sprintf("no files match %q", arg)}
+			}
+
+			paths = matches
+		}
+
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+
+			if err != nil {
+				return nil, ioError{err}
+			}
+
+			sources = append(sources, lexer.NewSourceFromBytes(path, data))
+		}
+	}
+
+	return sources, nil
+}
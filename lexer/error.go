@@ -0,0 +1,122 @@
+package lexer
+
+import (
+	"fmt"
+)
+
+type LexErrorReason uint8
+
+const (
+	UnknownCharacterLexErrorReason LexErrorReason = iota
+	UnterminatedStringLexErrorReason
+	UnterminatedCharacterLexErrorReason
+	InvalidEscapeLexErrorReason
+	InvalidCharacterLiteralLexErrorReason
+	InvalidDigitLexErrorReason
+	MalformedNumberLexErrorReason
+	UnterminatedQuotedIdentifierLexErrorReason
+	EmptyQuotedIdentifierLexErrorReason
+)
+
+func (r LexErrorReason) ToDisplayString() string {
+	switch r {
+	case UnknownCharacterLexErrorReason:
+		return "Unknown character"
+	case UnterminatedStringLexErrorReason:
+		return "Unterminated string literal"
+	case UnterminatedCharacterLexErrorReason:
+		return "Unterminated character literal"
+	case InvalidEscapeLexErrorReason:
+		return "Invalid escape sequence"
+	case InvalidCharacterLiteralLexErrorReason:
+		return "Invalid character literal"
+	case InvalidDigitLexErrorReason:
+		return "Invalid digit for base"
+	case MalformedNumberLexErrorReason:
+		return "Malformed number literal"
+	case UnterminatedQuotedIdentifierLexErrorReason:
+		return "Unterminated backtick identifier"
+	case EmptyQuotedIdentifierLexErrorReason:
+		return "Empty backtick identifier"
+	}
+
+	return "Unknown"
+}
+
+// An error in the source text found while lexing it.
+// In error-token mode (LexerOptions.ErrorTokens), these are collected by the lexer instead of being returned.
+type LexError struct {
+	Loc    Location
+	Reason LexErrorReason
+	// Extra detail about this particular error, which may be empty.
+	Detail string
+}
+
+func InitLexError(loc Location, reason LexErrorReason, detail string) LexError {
+	return LexError{
+		Loc:    loc,
+		Reason: reason,
+		Detail: detail,
+	}
+}
+
+func (e LexError) Error() string {
+	msg := fmt.Sprintf(
+		"%s at %d:%d",
+		e.Reason.ToDisplayString(),
+		e.Loc.Start.line,
+		e.Loc.Start.column,
+	)
+
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+
+	return msg
+}
+
+type LexWarningReason uint8
+
+const (
+	ConfusableCharacterLexWarningReason LexWarningReason = iota
+)
+
+func (r LexWarningReason) ToDisplayString() string {
+	switch r {
+	case ConfusableCharacterLexWarningReason:
+		return "Confusable character"
+	}
+
+	return "Unknown"
+}
+
+// Something in the source text that is allowed, but is probably a mistake.
+type LexWarning struct {
+	Loc    Location
+	Reason LexWarningReason
+	// Extra detail about this particular warning, which may be empty.
+	Detail string
+}
+
+func InitLexWarning(loc Location, reason LexWarningReason, detail string) LexWarning {
+	return LexWarning{
+		Loc:    loc,
+		Reason: reason,
+		Detail: detail,
+	}
+}
+
+func (w LexWarning) String() string {
+	msg := fmt.Sprintf(
+		"%s at %d:%d",
+		w.Reason.ToDisplayString(),
+		w.Loc.Start.line,
+		w.Loc.Start.column,
+	)
+
+	if w.Detail != "" {
+		msg += ": " + w.Detail
+	}
+
+	return msg
+}
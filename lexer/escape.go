@@ -0,0 +1,204 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Decodes the escape sequence at the start of s, which must begin just after the backslash.
+// Returns the rune the sequence represents and the number of bytes of s that it spans.
+//
+// Supported escapes are \n, \t, \r, \0, \\, \', \", \xHH (up to \x7F) and \u{H...} (any Unicode scalar value).
+func decodeEscape(s string) (rune, int, error) {
+	if len(s) == 0 {
+		return 0, 0, fmt.Errorf("Escape sequence is missing its character")
+	}
+
+	switch s[0] {
+	case 'n':
+		return '\n', 1, nil
+	case 't':
+		return '\t', 1, nil
+	case 'r':
+		return '\r', 1, nil
+	case '0':
+		return 0, 1, nil
+	case '\\':
+		return '\\', 1, nil
+	case '\'':
+		return '\'', 1, nil
+	case '"':
+		return '"', 1, nil
+	case 'x':
+		if len(s) < 3 {
+			return 0, 0, fmt.Errorf("Escape sequence \\x must be followed by two hexadecimal digits")
+		}
+
+		n, err := strconv.ParseUint(s[1:3], 16, 8)
+
+		if err != nil {
+			return 0, 0, fmt.Errorf("Escape sequence \\x must be followed by two hexadecimal digits, got %q", s[1:3])
+		}
+
+		if n > 0x7F {
+			return 0, 0, fmt.Errorf("Escape sequence \\x%s is out of range, use \\u{...} for characters above \\x7F", s[1:3])
+		}
+
+		return rune(n), 3, nil
+	case 'u':
+		if len(s) < 2 || s[1] != '{' {
+			return 0, 0, fmt.Errorf("Escape sequence \\u must be followed by hexadecimal digits in braces, like \\u{1F525}")
+		}
+
+		end := strings.IndexByte(s, '}')
+
+		if end == -1 {
+			return 0, 0, fmt.Errorf("Escape sequence \\u{...} is missing its closing '}'")
+		}
+
+		digits := s[2:end]
+
+		if len(digits) == 0 || len(digits) > 6 {
+			return 0, 0, fmt.Errorf("Escape sequence \\u{%s} must contain between 1 and 6 hexadecimal digits", digits)
+		}
+
+		n, err := strconv.ParseUint(digits, 16, 32)
+
+		if err != nil {
+			return 0, 0, fmt.Errorf("Escape sequence \\u{%s} contains a non-hexadecimal digit", digits)
+		}
+
+		if !utf8.ValidRune(rune(n)) {
+			return 0, 0, fmt.Errorf("Escape sequence \\u{%s} is not a valid Unicode scalar value", digits)
+		}
+
+		return rune(n), end + 1, nil
+	}
+
+	r, _ := utf8.DecodeRuneInString(s)
+
+	return 0, 0, fmt.Errorf("Unknown escape sequence \\%c", r)
+}
+
+// Decodes the spelling of a character literal, including its quotes, into the single Unicode scalar value it represents.
+func DecodeCharacterLiteral(spelling string) (rune, error) {
+	if len(spelling) < 2 || spelling[0] != '\'' || spelling[len(spelling)-1] != '\'' {
+		return 0, fmt.Errorf("Character literal %s is not surrounded by single quotes", spelling)
+	}
+
+	spelling = spelling[1 : len(spelling)-1]
+
+	if len(spelling) == 0 {
+		return 0, fmt.Errorf("Character literal is empty")
+	}
+
+	var (
+		r    rune
+		size int
+	)
+
+	if spelling[0] == '\\' {
+		er, n, err := decodeEscape(spelling[1:])
+
+		if err != nil {
+			return 0, err
+		}
+
+		r, size = er, n+1
+	} else {
+		r, size = utf8.DecodeRuneInString(spelling)
+
+		if r == utf8.RuneError && size <= 1 {
+			return 0, fmt.Errorf("Character literal contains invalid UTF-8")
+		}
+	}
+
+	if size != len(spelling) {
+		return 0, fmt.Errorf("Character literal '%s' contains more than one character", spelling)
+	}
+
+	return r, nil
+}
+
+// Decodes the spelling of a string literal, including its quotes and any 'r' prefix, into the string it represents.
+//
+// Raw strings (r"...") are returned exactly as written.
+// Multiline strings ("""...""") drop the line break after the opening quotes, and if the closing quotes are on
+// their own line, that line and its indentation are removed from every line of the string.
+func DecodeStringLiteral(spelling string) (string, error) {
+	raw := strings.HasPrefix(spelling, "r")
+
+	if raw {
+		spelling = spelling[1:]
+	}
+
+	var body string
+
+	if len(spelling) >= 6 && strings.HasPrefix(spelling, `"""`) && strings.HasSuffix(spelling, `"""`) {
+		b, err := stripMultilineIndentation(spelling[3 : len(spelling)-3])
+
+		if err != nil {
+			return "", err
+		}
+
+		body = b
+	} else if len(spelling) >= 2 && spelling[0] == '"' && spelling[len(spelling)-1] == '"' {
+		body = spelling[1 : len(spelling)-1]
+	} else {
+		return "", fmt.Errorf("String literal %s is not surrounded by double quotes", spelling)
+	}
+
+	if raw {
+		return body, nil
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(body); {
+		if body[i] != '\\' {
+			sb.WriteByte(body[i])
+			i++
+
+			continue
+		}
+
+		r, n, err := decodeEscape(body[i+1:])
+
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteRune(r)
+		i += n + 1
+	}
+
+	return sb.String(), nil
+}
+
+func stripMultilineIndentation(body string) (string, error) {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.TrimPrefix(body, "\n")
+
+	lastBreak := strings.LastIndexByte(body, '\n')
+
+	if lastBreak == -1 || strings.TrimLeft(body[lastBreak+1:], " \t") != "" {
+		return body, nil
+	}
+
+	indent := body[lastBreak+1:]
+	lines := strings.Split(body[:lastBreak], "\n")
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, indent) {
+			lines[i] = line[len(indent):]
+		} else if strings.TrimLeft(line, " \t") == "" {
+			lines[i] = ""
+		} else {
+			return "", fmt.Errorf("Line %d of multiline string literal is indented less than its closing quotes", i+1)
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
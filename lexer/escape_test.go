@@ -0,0 +1,130 @@
+package lexer
+
+import (
+	"testing"
+)
+
+func TestDecodeStringLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		spelling string
+		want     string
+	}{
+		{"plain", `"hello"`, "hello"},
+		{"empty", `""`, ""},
+		{"newline escape", `"a\nb"`, "a\nb"},
+		{"tab escape", `"a\tb"`, "a\tb"},
+		{"carriage return escape", `"a\rb"`, "a\rb"},
+		{"null escape", `"a\0b"`, "a\x00b"},
+		{"backslash escape", `"a\\b"`, `a\b`},
+		{"single quote escape", `"\'"`, "'"},
+		{"double quote escape", `"\""`, `"`},
+		{"hexadecimal escape", `"\x41\x7F"`, "A\x7f"},
+		{"unicode escape", `"\u{1F525}"`, "\U0001F525"},
+		{"short unicode escape", `"\u{E9}"`, "é"},
+		{"raw", `r"a\nb\x"`, `a\nb\x`},
+		{"raw multiline", "r\"\"\"\n    a\\n\n    \"\"\"", `a\n`},
+		{"multiline", "\"\"\"\n    first\n      second\n    \"\"\"", "first\n  second"},
+		{"multiline with escapes", "\"\"\"\n    a\\tb\n    \"\"\"", "a\tb"},
+		{"multiline with blank line", "\"\"\"\n    a\n\n    b\n    \"\"\"", "a\n\nb"},
+		{"multiline with CRLF", "\"\"\"\r\n    first\r\n    second\r\n    \"\"\"", "first\nsecond"},
+		{"multiline closed on the last line", "\"\"\"\n    kept\"\"\"", "    kept"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DecodeStringLiteral(test.spelling)
+
+			if err != nil {
+				t.Fatalf("decoding %q: %v", test.spelling, err)
+			}
+
+			if got != test.want {
+				t.Errorf("decoding %q: got %q, want %q", test.spelling, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidStringLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		spelling string
+	}{
+		{"unknown escape", `"\q"`},
+		{"trailing backslash", "\"\\\""},
+		{"hexadecimal escape with one digit", `"\x4"`},
+		{"hexadecimal escape with a non-hexadecimal digit", `"\x4G"`},
+		{"hexadecimal escape above 7F", `"\x80"`},
+		{"unicode escape without braces", `"\u1F525"`},
+		{"unicode escape without closing brace", `"\u{1F525"`},
+		{"empty unicode escape", `"\u{}"`},
+		{"unicode escape with too many digits", `"\u{0000041}"`},
+		{"unicode escape with a non-hexadecimal digit", `"\u{12G}"`},
+		{"unicode escape for a surrogate", `"\u{D800}"`},
+		{"unicode escape above the last scalar value", `"\u{110000}"`},
+		{"under-indented line", "\"\"\"\n    first\n  second\n    \"\"\""},
+		{"under-indented line with CRLF", "\"\"\"\r\n    first\r\n  second\r\n    \"\"\""},
+		{"missing quotes", "hello"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := DecodeStringLiteral(test.spelling); err == nil {
+				t.Errorf("decoding %q: got %q, want an error", test.spelling, got)
+			}
+		})
+	}
+}
+
+func TestDecodeCharacterLiteral(t *testing.T) {
+	tests := []struct {
+		spelling string
+		want     rune
+	}{
+		{"'a'", 'a'},
+		{"'é'", 'é'},
+		{"'\U0001F525'", '\U0001F525'},
+		{`'\n'`, '\n'},
+		{`'\''`, '\''},
+		{`'\\'`, '\\'},
+		{`'\x41'`, 'A'},
+		{`'\u{1F525}'`, '\U0001F525'},
+	}
+
+	for _, test := range tests {
+		got, err := DecodeCharacterLiteral(test.spelling)
+
+		if err != nil {
+			t.Errorf("decoding %s: %v", test.spelling, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("decoding %s: got %q, want %q", test.spelling, got, test.want)
+		}
+	}
+}
+
+func TestDecodeInvalidCharacterLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		spelling string
+	}{
+		{"empty", "''"},
+		{"two characters", "'ab'"},
+		{"escape followed by a character", `'\na'`},
+		{"combining sequence", "'e\u0301'"},
+		{"unknown escape", `'\q'`},
+		{"invalid UTF-8", "'\xff'"},
+		{"missing quotes", "a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := DecodeCharacterLiteral(test.spelling); err == nil {
+				t.Errorf("decoding %q: got %q, want an error", test.spelling, got)
+			}
+		})
+	}
+}
@@ -2,12 +2,16 @@ package lexer
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"iter"
 	"log"
-	"os"
-	"strconv"
+	"slices"
 	"strings"
+	"unicode"
 
+	"golang.org/x/text/unicode/norm"
 	"ljpprojects.org/sqopl/utils"
 )
 
@@ -20,117 +24,206 @@ const (
 	Base16LexerNumericalBase
 )
 
+// What sets each base apart when lexing a number literal.
+type numericalBaseInfo struct {
+	radix int
+	// The prefix that introduces a literal in this base, or "" for base 10.
+	prefix string
+	digits string
+	// The characters that start an exponent, or "" if literals in this base are always integers.
+	exponentMarkers string
+}
+
+var numericalBases = map[LexerNumericalBase]numericalBaseInfo{
+	Base2LexerNumericalBase:  {radix: 2, prefix: "0b", digits: "01"},
+	Base8LexerNumericalBase:  {radix: 8, prefix: "0o", digits: "01234567"},
+	Base10LexerNumericalBase: {radix: 10, prefix: "", digits: "0123456789", exponentMarkers: "eE"},
+	Base16LexerNumericalBase: {radix: 16, prefix: "0x", digits: "0123456789ABCDEFabcdef", exponentMarkers: "pP"},
+}
+
+// Returns the number of distinct digits in the base, such as 16 for Base16LexerNumericalBase.
+func (b LexerNumericalBase) Radix() int {
+	return numericalBases[b].radix
+}
+
+// Returns the prefix that introduces a literal in the base, such as "0x", or "" for base 10.
+func (b LexerNumericalBase) Prefix() string {
+	return numericalBases[b].prefix
+}
+
+// A point in a source, between two characters.
+// Lines and columns count from 1, while the byte offset counts from 0.
 type Position struct {
 	line   uint32
 	column uint32
+	offset uint64
+	source SourceID
+}
+
+func InitPosition(line uint32, column uint32, offset uint64, source SourceID) Position {
+	return Position{
+		line:   line,
+		column: column,
+		offset: offset,
+		source: source,
+	}
+}
+
+func (p Position) Line() uint32 {
+	return p.line
+}
+
+func (p Position) Column() uint32 {
+	return p.column
+}
+
+// Returns the number of bytes between the start of the source and this position.
+func (p Position) Offset() uint64 {
+	return p.offset
+}
+
+func (p Position) SourceID() SourceID {
+	return p.source
 }
 
 type Lexer struct {
 	currentPosition    Position
 	reader             *bufio.Reader
-	file               *os.File
+	source             *Source
+	options            LexerOptions
 	justSkippedNewline bool
-	bytesRead          uint64
+	lastRuneOffset     uint64
+
+	// Tokens that have been scanned but possibly not yet consumed.
+	// Tokens before lookaheadCursor have been consumed, and are only kept while a mark is held.
+	lookahead       []Token
+	lookaheadCursor int
+	heldMarks       int
+	scannedEOF      bool
+	lastTokenEnd    Position
+
+	// Errors collected in error-token mode.
+	errors []LexError
+	// Warnings collected regardless of mode.
+	warnings []LexWarning
+
+	endOfFileTrivia []Trivia
+}
+
+// A saved position in the token stream, created by Lexer.Mark.
+type LexerMark struct {
+	cursor       int
+	lastTokenEnd Position
+}
+
+func NewLexer(source *Source) *Lexer {
+	return NewLexerWithOptions(source, DefaultLexerOptions())
 }
 
-func NewLexer(file *os.File) *Lexer {
+func NewLexerWithOptions(source *Source, options LexerOptions) *Lexer {
 	l := new(Lexer)
 
-	l.currentPosition = Position{
-		line:   1,
-		column: 1,
-	}
+	l.currentPosition = InitPosition(1, 1, 0, source.ID())
 
-	l.file = file
-	l.reader = bufio.NewReader(file)
+	l.source = source
+	l.options = options
+	l.reader = bufio.NewReader(bytes.NewReader(source.Bytes()))
 	l.justSkippedNewline = false
-	l.bytesRead = 0
+	l.lastTokenEnd = l.currentPosition
 
 	return l
 }
 
-func (l *Lexer) Clone() (*Lexer, error) {
-	file, err := os.Open(l.file.Name())
+// Shorthand for NewLexer(NewSourceFromReader(name, r)).
+func NewLexerFromReader(name string, r io.Reader) (*Lexer, error) {
+	source, err := NewSourceFromReader(name, r)
 
 	if err != nil {
 		return nil, err
 	}
 
-	lexer := NewLexer(file)
-
-	lexer.currentPosition = l.currentPosition
-	lexer.justSkippedNewline = l.justSkippedNewline
-	lexer.reader.Discard(int(l.bytesRead))
-
-	return lexer, nil
+	return NewLexer(source), nil
 }
 
-func IsValidIdentStart(r rune) bool {
-	allowedChars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_"
+// Shorthand for NewLexer(NewSourceFromString(name, str)).
+func NewLexerFromString(name string, str string) *Lexer {
+	return NewLexer(NewSourceFromString(name, str))
+}
 
-	return strings.ContainsRune(allowedChars, r)
+func (l *Lexer) Source() *Source {
+	return l.source
 }
 
-func IsValidIdentPart(r rune) bool {
-	allowedChars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"
+// Creates an independent copy of the lexer that continues from the same position.
+// The copy shares the (immutable) source, so this never reads from the original reader or file.
+func (l *Lexer) Clone() (*Lexer, error) {
+	lexer := NewLexerWithOptions(l.source, l.options)
+
+	lexer.currentPosition = l.currentPosition
+	lexer.justSkippedNewline = l.justSkippedNewline
+	lexer.reader = bufio.NewReader(bytes.NewReader(l.source.Bytes()[l.currentPosition.offset:]))
+	lexer.lookahead = slices.Clone(l.lookahead)
+	lexer.lookaheadCursor = l.lookaheadCursor
+	lexer.heldMarks = l.heldMarks
+	lexer.scannedEOF = l.scannedEOF
+	lexer.lastTokenEnd = l.lastTokenEnd
+	lexer.errors = slices.Clone(l.errors)
+	lexer.warnings = slices.Clone(l.warnings)
+	lexer.endOfFileTrivia = l.endOfFileTrivia
 
-	return strings.ContainsRune(allowedChars, r)
+	return lexer, nil
 }
 
+// Reports whether r can appear unescaped in a single-line, non-raw string literal.
 func IsValidStringPart(r rune) bool {
-	forbiddenChars := "\"\t\n\r"
+	forbiddenChars := "\"\\\n\r"
 
 	return !strings.ContainsRune(forbiddenChars, r)
 }
 
 func IsValidNumberPart(r rune, base LexerNumericalBase) bool {
-	allowedChars := map[LexerNumericalBase]string{
-		Base2LexerNumericalBase:  "01",
-		Base8LexerNumericalBase:  "01234567",
-		Base10LexerNumericalBase: "0123456789",
-		Base16LexerNumericalBase: "0123456789ABCDEFabcdef",
-	}
-
-	return strings.ContainsRune(allowedChars[base], r)
+	return strings.ContainsRune(numericalBases[base].digits, r)
 }
 
+// Returns the position just after the last token returned by Lexer.NextToken.
+// Peeked tokens do not move this position.
 func (l Lexer) CurrentPos() Position {
-	return l.currentPosition
+	return l.lastTokenEnd
 }
 
 // Function to read a character from the lexer's reader.
 // By default, skips whitespace and automatically handles newlines.
+// Whitespace is any Unicode whitespace character; a "\r\n" pair is a single newline, as the '\r' is skipped as whitespace.
 // Use Lexer.readRuneDefault if you want  ashorthand way of calling Lexer.readRune(true, true)
 // Returns (None, nil) when EOF
 func (l *Lexer) readRune(skipWhitespace bool, autoHandleNewlines bool) (utils.Optional[rune], error) {
-	r, s, err := l.reader.ReadRune()
-	l.bytesRead += uint64(s)
+	for {
+		r, s, err := l.reader.ReadRune()
+		l.lastRuneOffset = l.currentPosition.offset
+		l.currentPosition.offset += uint64(s)
+
+		if err == io.EOF {
+			return utils.NoneOptional[rune](), nil
+		} else if err != nil {
+			return utils.NoneOptional[rune](), err
+		}
 
-	if err == io.EOF {
-		return utils.NoneOptional[rune](), nil
-	} else if err != nil {
-		return utils.NoneOptional[rune](), err
-	}
+		l.justSkippedNewline = false
 
-	l.justSkippedNewline = false
+		switch {
+		case r == '\n' && autoHandleNewlines:
+			l.currentPosition.line++
+			l.currentPosition.column = 1
 
-	switch {
-	case r == ' ' && skipWhitespace:
-		l.currentPosition.column++
+			log.Println("NEWLINE", l.currentPosition)
+		case r != '\n' && unicode.IsSpace(r) && skipWhitespace:
+			l.currentPosition.column = l.options.advanceColumn(l.currentPosition.column, r)
+		default:
+			l.currentPosition.column = l.options.advanceColumn(l.currentPosition.column, r)
 
-		return l.readRune(skipWhitespace, autoHandleNewlines)
-	case r == '\n' && autoHandleNewlines:
-		l.currentPosition.line++
-		l.currentPosition.column = 1
-
-		log.Println("NEWLINE", l.currentPosition)
-
-		return l.readRune(skipWhitespace, autoHandleNewlines)
-	default:
-		l.currentPosition.column++
+			return utils.SomeOptional(r), nil
+		}
 	}
-
-	return utils.SomeOptional(r), nil
 }
 
 func (l *Lexer) peekBytes(n int) (utils.Optional[[]byte], error) {
@@ -146,63 +239,100 @@ func (l *Lexer) peekBytes(n int) (utils.Optional[[]byte], error) {
 	return utils.SomeOptional(a), nil
 }
 
-func (l *Lexer) readRuneDefault() (utils.Optional[rune], error) {
-	return l.readRune(true, true)
-}
-
-func (l *Lexer) PeekToken() (utils.Optional[Token], error) {
-	lexer, err := l.Clone()
+// Reports whether the next character could start an identifier, without consuming it.
+func (l *Lexer) atIdentifierStart() bool {
+	mr, err := l.peekRuneAt(0)
 
 	if err != nil {
-		return utils.NoneOptional[Token](), err
+		return false
 	}
 
-	return lexer.NextToken()
+	r, err := mr.Value()
+
+	return err == nil && IsValidIdentStart(r)
 }
 
-func (l *Lexer) NextToken() (utils.Optional[Token], error) {
-	maybeRune, err := l.readRuneDefault()
+// Reads identifier characters until one that cannot be part of an identifier, appending them to ident.
+// The result is spelt exactly as in the source; Token.IdentifierName gives the normalised name.
+func (l *Lexer) readIdentifierRest(ident string) (string, error) {
+	for {
+		mr, err := l.peekRuneAt(0)
 
-	// Check for EOF
-	if err == io.EOF {
-		return utils.NoneOptional[Token](), nil
-	} else if err != nil {
-		return utils.NoneOptional[Token](), err
+		if err != nil {
+			return "", err
+		}
+
+		r, err := mr.Value()
+
+		if err != nil || !IsValidIdentPart(r) {
+			return ident, nil
+		}
+
+		if _, err := l.readRune(false, false); err != nil {
+			return "", err
+		}
+
+		ident += string(r)
 	}
+}
 
-	r, err := maybeRune.Value()
+// Returns the byte n bytes ahead of the reader without consuming anything.
+// Returns (None, nil) if the source ends first.
+func (l *Lexer) peekByte(n int) (utils.Optional[byte], error) {
+	mb, err := l.peekBytes(n + 1)
 
-	startpos := Position{
-		line:   l.currentPosition.line,
-		column: l.currentPosition.column - 1,
+	if err != nil {
+		return utils.NoneOptional[byte](), err
 	}
 
+	return utils.OptionalMap(mb, func(b []byte) byte { return b[n] }), nil
+}
+
+// Reports whether the byte n bytes ahead of the reader is b.
+func (l *Lexer) peekByteIs(n int, b byte) bool {
+	mb, err := l.peekByte(n)
+
 	if err != nil {
-		return utils.NoneOptional[Token](), nil
+		return false
 	}
 
-	switch {
-	case strings.ContainsRune(string(TokenOperatorGroup), r):
-		return utils.SomeOptional(InitToken(&TokenOperatorGroup, string(r), InitLocation(startpos, l.currentPosition))), nil
-	case strings.ContainsRune(string(TokenGroupingGroup), r):
-		return utils.SomeOptional(InitToken(&TokenGroupingGroup, string(r), InitLocation(startpos, l.currentPosition))), nil
-	case strings.ContainsRune(string(TokenSeparatorGroup), r):
-		return utils.SomeOptional(InitToken(&TokenSeparatorGroup, string(r), InitLocation(startpos, l.currentPosition))), nil
-	case r == '#':
-		line, _, err := l.reader.ReadLine()
-		l.bytesRead += uint64(len(line))
+	p, err := mb.Value()
 
-		if err != nil {
-			return utils.NoneOptional[Token](), err
+	return err == nil && p == b
+}
+
+// Reports whether the reader is at a line break, without consuming it.
+func (l *Lexer) atLineBreak() bool {
+	return l.peekByteIs(0, '\n') || l.peekByteIs(0, '\r')
+}
+
+// Scans the rest of a string literal whose opening quote, and 'r' prefix if raw, have already been read.
+// opening is the spelling read so far; the token keeps the complete spelling, and the decoded value is checked here.
+//
+// Three quotes open a multiline string, which ends at the next three quotes.
+// Single-line strings cannot contain line breaks, and raw strings do not process escape sequences.
+func (l *Lexer) scanString(startpos Position, opening string) (utils.Optional[Token], error) {
+	raw := strings.HasPrefix(opening, "r")
+	multiline := l.peekByteIs(0, '"') && l.peekByteIs(1, '"')
+	str := opening
+
+	if multiline {
+		for range 2 {
+			if _, err := l.readRune(false, false); err != nil {
+				return utils.NoneOptional[Token](), err
+			}
 		}
 
-		l.currentPosition.line++
-		l.currentPosition.column = 1
+		str += "\"\""
+	}
 
-		return l.NextToken()
-	case r == '"':
-		str := ""
-		mp, err := l.readRune(false, true)
+	for {
+		// Line breaks are left unread, so that the next token starts on the right line
+		if !multiline && l.atLineBreak() {
+			return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedStringLexErrorReason, "")
+		}
+
+		mp, err := l.readRune(false, false)
 
 		if err != nil {
 			return utils.NoneOptional[Token](), err
@@ -211,27 +341,41 @@ func (l *Lexer) NextToken() (utils.Optional[Token], error) {
 		p, err := mp.Value()
 
 		if err != nil {
-			return utils.NoneOptional[Token](), err
+			return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedStringLexErrorReason, "")
 		}
 
-		for {
-			str += string(p)
+		str += string(p)
 
-			mb, err := l.peekBytes(1)
+		if p == '\n' {
+			l.currentPosition.line++
+			l.currentPosition.column = 1
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
+			continue
+		}
+
+		if p == '"' {
+			if !multiline {
+				break
 			}
 
-			b, err := mb.Value()
+			if l.peekByteIs(0, '"') && l.peekByteIs(1, '"') {
+				for range 2 {
+					if _, err := l.readRune(false, false); err != nil {
+						return utils.NoneOptional[Token](), err
+					}
+				}
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+				str += "\"\""
 
-			if !IsValidStringPart(rune(b[0])) {
 				break
 			}
+		}
+
+		// The character after a backslash can never close the literal
+		if p == '\\' && !raw {
+			if !multiline && l.atLineBreak() {
+				return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedStringLexErrorReason, "")
+			}
 
 			mp, err := l.readRune(false, false)
 
@@ -239,203 +383,328 @@ func (l *Lexer) NextToken() (utils.Optional[Token], error) {
 				return utils.NoneOptional[Token](), err
 			}
 
-			p, err = mp.Value()
+			p, err := mp.Value()
 
 			if err != nil {
-				return utils.NoneOptional[Token](), err
+				return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedStringLexErrorReason, "")
 			}
-		}
 
-		if _, err := l.readRuneDefault(); err != nil {
-			return utils.NoneOptional[Token](), err
+			str += string(p)
+
+			if p == '\n' {
+				l.currentPosition.line++
+				l.currentPosition.column = 1
+			}
 		}
+	}
 
-		return utils.SomeOptional(InitToken(&TokenStringGroup, str, InitLocation(startpos, l.currentPosition))), nil
-	case r == '1' || r == '2' || r == '3' || r == '4' || r == '5' || r == '6' || r == '7' || r == '8' || r == '9':
-		var num int64 = 0
+	if _, err := DecodeStringLiteral(str); err != nil {
+		return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), InvalidEscapeLexErrorReason, err.Error())
+	}
 
-		str := string(r)
-		mp, err := l.readRune(false, false)
+	return utils.SomeOptional(InitToken(StringTokenKind, str, InitLocation(startpos, l.currentPosition))), nil
+}
 
-		if err != nil {
-			return utils.NoneOptional[Token](), err
-		}
+// Scans the longest operator in the operator table that starts with r, which has already been read.
+func (l *Lexer) scanOperator(startpos Position, r rune) (utils.Optional[Token], error) {
+	spelling := string(r)
 
-		p, err := mp.Value()
+	// Operator characters are all ASCII, so each one is a single byte
+	for n := 0; n < maxOperatorLength-1; n++ {
+		mb, err := l.peekByte(n)
 
 		if err != nil {
 			return utils.NoneOptional[Token](), err
 		}
 
-		for {
-			if !IsValidNumberPart(p, Base10LexerNumericalBase) {
-				break
-			}
+		b, err := mb.Value()
 
-			str += string(p)
+		if err != nil || !strings.ContainsRune(string(TokenOperatorGroup), rune(b)) {
+			break
+		}
 
-			mb, err := l.peekBytes(1)
+		spelling += string(rune(b))
+	}
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+	for OperatorKindFromSpelling(spelling) == NotAnOperatorKind {
+		spelling = spelling[:len(spelling)-1]
+	}
 
-			b, err := mb.Value()
+	for range len(spelling) - 1 {
+		if _, err := l.readRune(false, false); err != nil {
+			return utils.NoneOptional[Token](), err
+		}
+	}
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+	return utils.SomeOptional(InitToken(OperatorTokenKind, spelling, InitLocation(startpos, l.currentPosition))), nil
+}
 
-			if !IsValidNumberPart(rune(b[0]), Base10LexerNumericalBase) {
-				break
-			}
+func (l *Lexer) readRuneDefault() (utils.Optional[rune], error) {
+	return l.readRune(true, true)
+}
 
-			mp, err := l.readRune(false, false)
+// Scans tokens into the lookahead buffer until at least n unconsumed tokens are available, or EOF is reached.
+func (l *Lexer) fillLookahead(n int) error {
+	keepTrivia := l.options.KeepComments || l.options.KeepWhitespace
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+	for len(l.lookahead)-l.lookaheadCursor < n && !l.scannedEOF {
+		var leading []Trivia
 
-			p, err = mp.Value()
+		if keepTrivia {
+			trivia, err := l.scanTrivia(false)
 
 			if err != nil {
-				return utils.NoneOptional[Token](), err
+				return err
 			}
-		}
 
-		n, err := strconv.ParseInt(str, 10, 64)
-
-		if err != nil {
-			return utils.NoneOptional[Token](), err
+			leading = trivia
 		}
 
-		num = n
+		mtk, err := l.scanToken()
 
-		return utils.SomeOptional(InitToken(&TokenIntegerGroup, strconv.FormatInt(num, 10), InitLocation(startpos, l.currentPosition))), nil
-	case r == '0':
-		maybeRune, err := l.readRuneDefault()
+		// The offending text has already been skipped, so in error-token mode scanning can simply carry on
+		if lexErr, ok := err.(LexError); ok && l.options.ErrorTokens {
+			l.errors = append(l.errors, lexErr)
+			mtk, err = utils.SomeOptional(InitToken(ErrorTokenKind, l.source.Text(lexErr.Loc), lexErr.Loc)), nil
+		}
 
 		if err != nil {
-			return utils.NoneOptional[Token](), err
+			return err
 		}
 
-		r, err := maybeRune.Value()
-
-		var num int64 = 0
+		tk, err := mtk.Value()
 
 		if err != nil {
-			return utils.NoneOptional[Token](), err
+			l.scannedEOF = true
+			l.endOfFileTrivia = leading
+
+			break
 		}
 
-		switch r {
-		case 'b':
-			str := ""
-			mp, err := l.readRune(false, false)
+		if keepTrivia {
+			trailing, err := l.scanTrivia(true)
 
 			if err != nil {
-				return utils.NoneOptional[Token](), err
+				return err
 			}
 
-			p, err := mp.Value()
+			tk.leadingTrivia = leading
+			tk.trailingTrivia = trailing
+		}
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+		l.lookahead = append(l.lookahead, tk)
+	}
 
-			for {
-				str += string(p)
+	return nil
+}
 
-				mb, err := l.peekBytes(1)
+// Returns the trivia after the last token in the source.
+// This is only complete once Lexer.NextToken has returned EOF.
+func (l *Lexer) EndOfFileTrivia() []Trivia {
+	return l.endOfFileTrivia
+}
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
+// Returns every error found so far in error-token mode, in the order they appear in the source.
+func (l *Lexer) Errors() []LexError {
+	return l.errors
+}
+
+// Returns every warning found so far, in the order they appear in the source.
+// Warnings never stop lexing.
+func (l *Lexer) Warnings() []LexWarning {
+	return l.warnings
+}
 
-				b, err := mb.Value()
+// Returns the next token without consuming it.
+// Returns (None, nil) when EOF
+func (l *Lexer) PeekToken() (utils.Optional[Token], error) {
+	return l.PeekN(1)
+}
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
+// Returns the nth upcoming token without consuming anything, so PeekN(1) is equivalent to PeekToken.
+// Returns (None, nil) if the stream ends before the nth token.
+func (l *Lexer) PeekN(n int) (utils.Optional[Token], error) {
+	if n < 1 {
+		return utils.NoneOptional[Token](), nil
+	}
 
-				if !IsValidNumberPart(rune(b[0]), Base2LexerNumericalBase) {
-					break
-				}
+	if err := l.fillLookahead(n); err != nil {
+		return utils.NoneOptional[Token](), err
+	}
 
-				mp, err := l.readRune(false, false)
+	if len(l.lookahead)-l.lookaheadCursor < n {
+		return utils.NoneOptional[Token](), nil
+	}
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
+	return utils.SomeOptional(l.lookahead[l.lookaheadCursor+n-1]), nil
+}
+
+// Consumes and returns the next token.
+// Returns (None, nil) when EOF
+func (l *Lexer) NextToken() (utils.Optional[Token], error) {
+	if err := l.fillLookahead(1); err != nil {
+		return utils.NoneOptional[Token](), err
+	}
 
-				p, err = mp.Value()
+	if l.lookaheadCursor == len(l.lookahead) {
+		return utils.NoneOptional[Token](), nil
+	}
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
-			}
+	tk := l.lookahead[l.lookaheadCursor]
 
-			n, err := strconv.ParseInt(str, 2, 64)
+	l.lookaheadCursor++
+	l.lastTokenEnd = tk.Endpos()
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+	// Nothing can rewind to consumed tokens, so the buffer can be reused
+	if l.heldMarks == 0 && l.lookaheadCursor == len(l.lookahead) {
+		l.lookahead = l.lookahead[:0]
+		l.lookaheadCursor = 0
+	}
 
-			num = n
-		case 'o':
-			str := ""
-			mp, err := l.readRune(false, false)
+	return utils.SomeOptional(tk), nil
+}
+
+// Returns an iterator that consumes the remaining tokens one at a time.
+// If lexing fails, the error is yielded with a zero Token and iteration stops.
+// Breaking out of the loop leaves the unconsumed tokens in the lexer.
+func (l *Lexer) Tokens() iter.Seq2[Token, error] {
+	return func(yield func(Token, error) bool) {
+		for {
+			mtk, err := l.NextToken()
 
 			if err != nil {
-				return utils.NoneOptional[Token](), err
+				yield(Token{}, err)
+				return
 			}
 
-			p, err := mp.Value()
+			tk, err := mtk.Value()
 
 			if err != nil {
-				return utils.NoneOptional[Token](), err
+				return
 			}
 
-			for {
-				str += string(p)
+			if !yield(tk, nil) {
+				return
+			}
+		}
+	}
+}
 
-				mb, err := l.peekBytes(1)
+// Consumes every remaining token and returns them in order.
+// On error, the tokens before it are returned along with the error.
+func (l *Lexer) CollectTokens() ([]Token, error) {
+	var tokens []Token
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
+	for tk, err := range l.Tokens() {
+		if err != nil {
+			return tokens, err
+		}
 
-				b, err := mb.Value()
+		tokens = append(tokens, tk)
+	}
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
+	return tokens, nil
+}
 
-				if !IsValidNumberPart(rune(b[0]), Base8LexerNumericalBase) {
-					break
-				}
+// Saves the current position in the token stream so that it can later be returned to with Lexer.Rewind.
+// Every mark must be released with either Lexer.Rewind or Lexer.Commit.
+func (l *Lexer) Mark() LexerMark {
+	l.heldMarks++
 
-				mp, err := l.readRune(false, false)
+	return LexerMark{
+		cursor:       l.lookaheadCursor,
+		lastTokenEnd: l.lastTokenEnd,
+	}
+}
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
+// Returns to a position saved with Lexer.Mark and releases the mark.
+// Tokens consumed since the mark will be returned again by Lexer.NextToken.
+func (l *Lexer) Rewind(mark LexerMark) {
+	l.lookaheadCursor = mark.cursor
+	l.lastTokenEnd = mark.lastTokenEnd
+	l.heldMarks--
+}
 
-				p, err = mp.Value()
+// Releases a mark without rewinding, keeping every token consumed since it was made.
+func (l *Lexer) Commit(mark LexerMark) {
+	l.heldMarks--
+}
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
-			}
+// Scans a single token directly from the source, bypassing the lookahead buffer.
+func (l *Lexer) scanToken() (utils.Optional[Token], error) {
+	maybeRune, err := l.readRuneDefault()
 
-			n, err := strconv.ParseInt(str, 8, 64)
+	// Check for EOF
+	if err == io.EOF {
+		return utils.NoneOptional[Token](), nil
+	} else if err != nil {
+		return utils.NoneOptional[Token](), err
+	}
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
+	r, err := maybeRune.Value()
+
+	if err != nil {
+		return utils.NoneOptional[Token](), nil
+	}
+
+	startpos := l.currentPosition
+	startpos.column--
+	startpos.offset = l.lastRuneOffset
+
+	switch {
+	case strings.ContainsRune(string(TokenOperatorGroup), r):
+		return l.scanOperator(startpos, r)
+	case strings.ContainsRune(string(TokenGroupingGroup), r):
+		return utils.SomeOptional(InitToken(GroupingTokenKind, string(r), InitLocation(startpos, l.currentPosition))), nil
+	case strings.ContainsRune(string(TokenSeparatorGroup), r):
+		return utils.SomeOptional(InitToken(SeparatorTokenKind, string(r), InitLocation(startpos, l.currentPosition))), nil
+	case r == '#' && l.atIdentifierStart():
+		name, err := l.readIdentifierRest("#")
+
+		if err != nil {
+			return utils.NoneOptional[Token](), err
+		}
+
+		return utils.SomeOptional(InitToken(MacroNameTokenKind, name, InitLocation(startpos, l.currentPosition))), nil
+	case r == '$':
+		if l.peekByteIs(0, '(') {
+			return utils.SomeOptional(InitToken(MacroRepetitionTokenKind, "$", InitLocation(startpos, l.currentPosition))), nil
+		}
+
+		if !l.atIdentifierStart() {
+			return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnknownCharacterLexErrorReason, "'$' must be followed by a macro variable name or '('")
+		}
+
+		name, err := l.readIdentifierRest("$")
+
+		if err != nil {
+			return utils.NoneOptional[Token](), err
+		}
+
+		return utils.SomeOptional(InitToken(MacroVariableTokenKind, name, InitLocation(startpos, l.currentPosition))), nil
+	case r == '#':
+		// Only reached when trivia is not being kept, as scanTrivia reads comments otherwise
+		line, err := l.reader.ReadString('\n')
+		l.currentPosition.offset += uint64(len(line))
+
+		if err == io.EOF {
+			return utils.NoneOptional[Token](), nil
+		} else if err != nil {
+			return utils.NoneOptional[Token](), err
+		}
+
+		l.currentPosition.line++
+		l.currentPosition.column = 1
+
+		return l.scanToken()
+	case r == '`':
+		name := ""
+
+		for {
+			if l.atLineBreak() {
+				return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedQuotedIdentifierLexErrorReason, "expected a closing '`' before the end of the line")
 			}
 
-			num = n
-		case 'x':
-			str := ""
 			mp, err := l.readRune(false, false)
 
 			if err != nil {
@@ -445,28 +714,51 @@ func (l *Lexer) NextToken() (utils.Optional[Token], error) {
 			p, err := mp.Value()
 
 			if err != nil {
-				return utils.NoneOptional[Token](), err
+				return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedQuotedIdentifierLexErrorReason, "expected a closing '`' before the end of the file")
 			}
 
-			for {
-				str += string(p)
+			if p == '`' {
+				break
+			}
 
-				mb, err := l.peekBytes(1)
+			name += string(p)
+		}
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
+		if name == "" {
+			return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), EmptyQuotedIdentifierLexErrorReason, "")
+		}
 
-				b, err := mb.Value()
+		return utils.SomeOptional(InitToken(QuotedIdentifierTokenKind, "`"+name+"`", InitLocation(startpos, l.currentPosition))), nil
+	case r == '\'':
+		str := ""
 
-				if err != nil {
-					return utils.NoneOptional[Token](), err
-				}
+		for {
+			if l.atLineBreak() {
+				return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedCharacterLexErrorReason, "")
+			}
 
-				log.Println(string(b))
+			mp, err := l.readRune(false, false)
 
-				if !IsValidNumberPart(rune(b[0]), Base16LexerNumericalBase) {
-					break
+			if err != nil {
+				return utils.NoneOptional[Token](), err
+			}
+
+			p, err := mp.Value()
+
+			if err != nil {
+				return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedCharacterLexErrorReason, "")
+			}
+
+			if p == '\'' {
+				break
+			}
+
+			str += string(p)
+
+			// The character after a backslash can never close the literal
+			if p == '\\' {
+				if l.atLineBreak() {
+					return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedCharacterLexErrorReason, "")
 				}
 
 				mp, err := l.readRune(false, false)
@@ -475,77 +767,62 @@ func (l *Lexer) NextToken() (utils.Optional[Token], error) {
 					return utils.NoneOptional[Token](), err
 				}
 
-				p, err = mp.Value()
+				p, err := mp.Value()
 
 				if err != nil {
-					return utils.NoneOptional[Token](), err
+					return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnterminatedCharacterLexErrorReason, "")
 				}
+
+				str += string(p)
 			}
+		}
 
-			n, err := strconv.ParseInt(str, 16, 64)
+		str = "'" + str + "'"
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+		if _, err := DecodeCharacterLiteral(str); err != nil {
+			return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), InvalidCharacterLiteralLexErrorReason, err.Error())
+		}
 
-			num = n
+		return utils.SomeOptional(InitToken(CharacterTokenKind, str, InitLocation(startpos, l.currentPosition))), nil
+	case r == '"':
+		return l.scanString(startpos, "\"")
+	case r == 'r' && l.peekByteIs(0, '"'):
+		if _, err := l.readRune(false, false); err != nil {
+			return utils.NoneOptional[Token](), err
 		}
 
-		return utils.SomeOptional(InitToken(&TokenIntegerGroup, strconv.FormatInt(num, 10), InitLocation(startpos, l.currentPosition))), nil
+		return l.scanString(startpos, "r\"")
+	case IsValidNumberPart(r, Base10LexerNumericalBase):
+		return l.scanNumber(startpos, r)
 	}
 
 	if IsValidIdentStart(r) {
-		ident := string(r)
-		mp, err := l.readRune(false, true)
+		ident, err := l.readIdentifierRest(string(r))
 
 		if err != nil {
 			return utils.NoneOptional[Token](), err
 		}
 
-		p, err := mp.Value()
+		// Names which look the same are compared in Unicode Normalization Form C, so that they are spelt the same
+		name := norm.NFC.String(ident)
 
-		if err != nil {
-			return utils.NoneOptional[Token](), err
-		}
-
-		for {
-			if !IsValidIdentPart(p) {
-				break
-			}
-
-			ident += string(p)
-
-			mb, err := l.peekBytes(1)
-
-			if err != nil {
+		// "as?" is spelt like an identifier, but is an operator
+		if name == "as" && l.peekByteIs(0, '?') {
+			if _, err := l.readRune(false, false); err != nil {
 				return utils.NoneOptional[Token](), err
 			}
 
-			b, err := mb.Value()
-
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
-
-			if !IsValidIdentPart(rune(b[0])) {
-				break
-			}
-
-			mp, err := l.readRune(false, false)
-
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+			return utils.SomeOptional(InitOperatorToken(OptionalCastOperatorKind, InitLocation(startpos, l.currentPosition))), nil
+		}
 
-			p, err = mp.Value()
+		l.warnConfusables(name, InitLocation(startpos, l.currentPosition))
 
-			if err != nil {
-				return utils.NoneOptional[Token](), err
-			}
+		if keywordKindOfIdentifier(ident) != NotAKeywordKind {
+			return utils.SomeOptional(InitToken(KeywordTokenKind, ident, InitLocation(startpos, l.currentPosition))), nil
 		}
 
-		return utils.SomeOptional(InitToken(&TokenIdentifierGroup, ident, InitLocation(startpos, l.currentPosition))), nil
+		return utils.SomeOptional(InitToken(IdentifierTokenKind, ident, InitLocation(startpos, l.currentPosition))), nil
 	}
 
-	return utils.NoneOptional[Token](), nil
+	return utils.NoneOptional[Token](), InitLexError(InitLocation(startpos, l.currentPosition), UnknownCharacterLexErrorReason, fmt.Sprintf("%#U", r))
 }
@@ -0,0 +1,468 @@
+package lexer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIdentifierKeepsSourceSpelling(t *testing.T) {
+	// "café" written with a combining acute accent, which normalises to the single character 'é'
+	decomposed := "cafe\u0301"
+
+	tokens, err := NewLexerFromString("test", decomposed+" caf\u00e9").CollectTokens()
+
+	if err != nil {
+		t.Fatalf("lexing: %v", err)
+	}
+
+	if len(tokens) != 2 {
+		t.Fatalf("got %d tokens, want 2", len(tokens))
+	}
+
+	if tokens[0].Characters() != decomposed {
+		t.Errorf("Characters() is %q, want the source spelling %q", tokens[0].Characters(), decomposed)
+	}
+
+	if tokens[0].IdentifierName() != tokens[1].IdentifierName() {
+		t.Errorf("IdentifierName() is %q and %q, want both spellings to have the same name", tokens[0].IdentifierName(), tokens[1].IdentifierName())
+	}
+}
+
+// Consumes the next token and checks that it is spelt want.
+func expectNext(t *testing.T, l *Lexer, want string) {
+	t.Helper()
+
+	mtk, err := l.NextToken()
+
+	if err != nil {
+		t.Fatalf("NextToken: %v", err)
+	}
+
+	tk, err := mtk.Value()
+
+	if err != nil {
+		t.Fatalf("NextToken returned EOF, want %q", want)
+	}
+
+	if tk.Characters() != want {
+		t.Fatalf("NextToken returned %s, want %q", tk.ToDisplayString(), want)
+	}
+}
+
+func TestPeekN(t *testing.T) {
+	l := NewLexerFromString("test", "a b c")
+
+	for n, want := range map[int]string{3: "c", 1: "a", 2: "b"} {
+		mtk, err := l.PeekN(n)
+
+		if err != nil {
+			t.Fatalf("PeekN(%d): %v", n, err)
+		}
+
+		if tk, err := mtk.Value(); err != nil || tk.Characters() != want {
+			t.Errorf("PeekN(%d) returned %v, want %q", n, mtk, want)
+		}
+	}
+
+	for _, n := range []int{0, 4} {
+		mtk, err := l.PeekN(n)
+
+		if err != nil {
+			t.Fatalf("PeekN(%d): %v", n, err)
+		}
+
+		if _, err := mtk.Value(); err == nil {
+			t.Errorf("PeekN(%d) returned a token, want None", n)
+		}
+	}
+
+	// Peeking must not consume anything
+	expectNext(t, l, "a")
+	expectNext(t, l, "b")
+	expectNext(t, l, "c")
+}
+
+func TestRewindAcrossBufferReuse(t *testing.T) {
+	l := NewLexerFromString("test", "a b c d")
+
+	// Consuming every buffered token lets the buffer be reused, which must not happen while a mark is held
+	expectNext(t, l, "a")
+
+	mark := l.Mark()
+
+	expectNext(t, l, "b")
+	expectNext(t, l, "c")
+
+	l.Rewind(mark)
+
+	expectNext(t, l, "b")
+	expectNext(t, l, "c")
+	expectNext(t, l, "d")
+
+	if pos := l.CurrentPos(); pos.Column() != 8 {
+		t.Errorf("CurrentPos() is at column %d after the last token, want 8", pos.Column())
+	}
+}
+
+func TestNestedMarks(t *testing.T) {
+	l := NewLexerFromString("test", "a b c")
+
+	outer := l.Mark()
+
+	expectNext(t, l, "a")
+
+	inner := l.Mark()
+
+	expectNext(t, l, "b")
+
+	l.Rewind(inner)
+
+	expectNext(t, l, "b")
+
+	l.Rewind(outer)
+
+	expectNext(t, l, "a")
+}
+
+func TestCommit(t *testing.T) {
+	l := NewLexerFromString("test", "a b c")
+
+	mark := l.Mark()
+
+	expectNext(t, l, "a")
+	expectNext(t, l, "b")
+
+	l.Commit(mark)
+
+	// Committing keeps the consumed tokens consumed
+	expectNext(t, l, "c")
+
+	mtk, err := l.NextToken()
+
+	if err != nil {
+		t.Fatalf("NextToken: %v", err)
+	}
+
+	if _, err := mtk.Value(); err == nil {
+		t.Errorf("NextToken returned a token after the last one, want None")
+	}
+
+	if l.heldMarks != 0 {
+		t.Errorf("%d marks are still held after Commit, want 0", l.heldMarks)
+	}
+}
+
+func TestLineIndexPosition(t *testing.T) {
+	source := NewSourceFromString("test", "let a = 1;\n\tb\r\nλx = 'é';\n")
+	index := NewLineIndex(source)
+
+	tests := []struct {
+		offset uint64
+		line   uint32
+		column uint32
+	}{
+		{0, 1, 1},
+		{4, 1, 5},
+		{10, 1, 11},
+		{11, 2, 1},
+		// After the tab, which moves to the next tab stop
+		{12, 2, 5},
+		{13, 2, 6},
+		{15, 3, 1},
+		// 'λ' is two bytes but one column
+		{17, 3, 2},
+		{24, 3, 8},
+		{27, 4, 1},
+		// Offsets past the end are clamped to the end of the source
+		{100, 4, 1},
+	}
+
+	for _, test := range tests {
+		pos := index.Position(test.offset)
+
+		if pos.Line() != test.line || pos.Column() != test.column {
+			t.Errorf("offset %d: got %d:%d, want %d:%d", test.offset, pos.Line(), pos.Column(), test.line, test.column)
+		}
+
+		if pos.SourceID() != source.ID() {
+			t.Errorf("offset %d: got source %d, want %d", test.offset, pos.SourceID(), source.ID())
+		}
+	}
+
+	if index.LineCount() != 4 {
+		t.Errorf("got %d lines, want 4", index.LineCount())
+	}
+
+	lines := []string{"", "let a = 1;", "\tb", "λx = 'é';", "", ""}
+
+	for line, want := range lines {
+		if got := index.LineText(uint32(line)); got != want {
+			t.Errorf("line %d: got %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestLineIndexMatchesLexer(t *testing.T) {
+	source := NewSourceFromString("test", "fn Main {\r\n\tlet s = \"λ\";\r\n    return;\n}")
+	index := NewLineIndex(source)
+	tokens, err := NewLexer(source).CollectTokens()
+
+	if err != nil {
+		t.Fatalf("lexing: %v", err)
+	}
+
+	for _, tk := range tokens {
+		for _, pos := range []Position{tk.Startpos(), tk.Endpos()} {
+			if got := index.Position(pos.Offset()); got != pos {
+				t.Errorf("token %s: index gives %d:%d, lexer gives %d:%d", tk.ToDisplayString(), got.Line(), got.Column(), pos.Line(), pos.Column())
+			}
+		}
+
+		if text := source.Text(InitLocation(tk.Startpos(), tk.Endpos())); text != tk.Characters() {
+			t.Errorf("token %s spans %q in the source", tk.ToDisplayString(), text)
+		}
+	}
+}
+
+func TestWhitespacePositions(t *testing.T) {
+	type expectedToken struct {
+		characters string
+		line       uint32
+		column     uint32
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		tabWidth uint32
+		tokens   []expectedToken
+	}{
+		{"spaces", "a  b", 4, []expectedToken{{"a", 1, 1}, {"b", 1, 4}}},
+		{"tab from the first column", "\ta", 4, []expectedToken{{"a", 1, 5}}},
+		{"tab part way to a stop", "ab\tc", 4, []expectedToken{{"ab", 1, 1}, {"c", 1, 5}}},
+		{"tab at a stop", "abcd\te", 4, []expectedToken{{"abcd", 1, 1}, {"e", 1, 9}}},
+		{"two tabs", "\t\ta", 4, []expectedToken{{"a", 1, 9}}},
+		{"tab width 8", "a\tb", 8, []expectedToken{{"a", 1, 1}, {"b", 1, 9}}},
+		{"tab width 1", "\t\ta", 1, []expectedToken{{"a", 1, 3}}},
+		{"CRLF", "a\r\nb\r\n\r\nc", 4, []expectedToken{{"a", 1, 1}, {"b", 2, 1}, {"c", 4, 1}}},
+		{"CRLF after a comment", "# comment\r\na", 4, []expectedToken{{"a", 2, 1}}},
+		{"lone CR", "a\rb", 4, []expectedToken{{"a", 1, 1}, {"b", 1, 3}}},
+		{"Unicode spaces", "a\u00a0b\u3000c", 4, []expectedToken{{"a", 1, 1}, {"b", 1, 3}, {"c", 1, 5}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := DefaultLexerOptions()
+			options.TabWidth = test.tabWidth
+
+			tokens, err := NewLexerWithOptions(NewSourceFromString("test", test.source), options).CollectTokens()
+
+			if err != nil {
+				t.Fatalf("lexing %q: %v", test.source, err)
+			}
+
+			if len(tokens) != len(test.tokens) {
+				t.Fatalf("lexing %q: got %d tokens, want %d", test.source, len(tokens), len(test.tokens))
+			}
+
+			for i, want := range test.tokens {
+				pos := tokens[i].Startpos()
+
+				if tokens[i].Characters() != want.characters || pos.Line() != want.line || pos.Column() != want.column {
+					t.Errorf("token %d: got %q at %d:%d, want %q at %d:%d", i, tokens[i].Characters(), pos.Line(), pos.Column(), want.characters, want.line, want.column)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorTokenRecovery(t *testing.T) {
+	options := DefaultLexerOptions()
+	options.ErrorTokens = true
+
+	l := NewLexerWithOptions(NewSourceFromString("test", "let a = @;\nlet b = 'xy';\nlet c = \"open\nlet d = 1;"), options)
+	tokens, err := l.CollectTokens()
+
+	if err != nil {
+		t.Fatalf("lexing: %v", err)
+	}
+
+	var errorTokens []Token
+
+	for _, tk := range tokens {
+		if tk.Kind() == ErrorTokenKind {
+			errorTokens = append(errorTokens, tk)
+		}
+	}
+
+	want := []struct {
+		characters string
+		reason     LexErrorReason
+		line       uint32
+	}{
+		{"@", UnknownCharacterLexErrorReason, 1},
+		{"'xy'", InvalidCharacterLiteralLexErrorReason, 2},
+		{"\"open", UnterminatedStringLexErrorReason, 3},
+	}
+
+	errs := l.Errors()
+
+	if len(errorTokens) != len(want) || len(errs) != len(want) {
+		t.Fatalf("got %d error tokens and %d errors, want %d of each", len(errorTokens), len(errs), len(want))
+	}
+
+	for i, w := range want {
+		if errorTokens[i].Characters() != w.characters || errorTokens[i].Startpos().Line() != w.line {
+			t.Errorf("error token %d: got %q on line %d, want %q on line %d", i, errorTokens[i].Characters(), errorTokens[i].Startpos().Line(), w.characters, w.line)
+		}
+
+		if errs[i].Reason != w.reason || errs[i].Loc != InitLocation(errorTokens[i].Startpos(), errorTokens[i].Endpos()) {
+			t.Errorf("error %d: got %s at %v, want %s at the error token", i, errs[i].Reason.ToDisplayString(), errs[i].Loc, w.reason.ToDisplayString())
+		}
+	}
+
+	// Lexing carries on after each error, so the last definition is still read
+	last := tokens[len(tokens)-5:]
+
+	if last[0].Keyword() != LetKeywordKind || last[1].IdentifierName() != "d" || last[4].Characters() != ";" {
+		t.Errorf("got last tokens %v, want let d = 1;", last)
+	}
+}
+
+func TestLexErrorWithoutErrorTokens(t *testing.T) {
+	l := NewLexerFromString("test", "a @ b")
+
+	expectNext(t, l, "a")
+
+	_, err := l.NextToken()
+
+	var lexErr LexError
+
+	if !errors.As(err, &lexErr) {
+		t.Fatalf("got error %v, want a LexError", err)
+	}
+
+	if lexErr.Reason != UnknownCharacterLexErrorReason || lexErr.Loc.Start.Column() != 3 {
+		t.Errorf("got %s at column %d, want an unknown character at column 3", lexErr.Reason.ToDisplayString(), lexErr.Loc.Start.Column())
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("got %d collected errors, want none outside error-token mode", len(l.Errors()))
+	}
+}
+
+func TestMacroTokens(t *testing.T) {
+	type expectedToken struct {
+		kind       TokenKind
+		characters string
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		tokens []expectedToken
+	}{
+		{"macro name", "#repeat", []expectedToken{{MacroNameTokenKind, "#repeat"}}},
+		{"macro call", "#list(1)", []expectedToken{{MacroNameTokenKind, "#list"}, {GroupingTokenKind, "("}, {IntegerTokenKind, "1"}, {GroupingTokenKind, ")"}}},
+		{"macro definition", "internal macro #repeat", []expectedToken{{KeywordTokenKind, "internal"}, {KeywordTokenKind, "macro"}, {MacroNameTokenKind, "#repeat"}}},
+		{"comment", "# repeat\nx", []expectedToken{{IdentifierTokenKind, "x"}}},
+		{"comment without a space", "#1 is not a name\nx", []expectedToken{{IdentifierTokenKind, "x"}}},
+		{"doc comment", "## Docs\nx", []expectedToken{{IdentifierTokenKind, "x"}}},
+		{"comment after a macro name", "#repeat # comment", []expectedToken{{MacroNameTokenKind, "#repeat"}}},
+		{"macro variable", "$item", []expectedToken{{MacroVariableTokenKind, "$item"}}},
+		{"macro repetition", "$($item),*", []expectedToken{
+			{MacroRepetitionTokenKind, "$"},
+			{GroupingTokenKind, "("},
+			{MacroVariableTokenKind, "$item"},
+			{GroupingTokenKind, ")"},
+			{SeparatorTokenKind, ","},
+			{OperatorTokenKind, "*"},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := NewLexerFromString("test", test.source).CollectTokens()
+
+			if err != nil {
+				t.Fatalf("lexing %q: %v", test.source, err)
+			}
+
+			if len(tokens) != len(test.tokens) {
+				t.Fatalf("lexing %q: got %d tokens, want %d", test.source, len(tokens), len(test.tokens))
+			}
+
+			for i, want := range test.tokens {
+				if tokens[i].Kind() != want.kind || tokens[i].Characters() != want.characters {
+					t.Errorf("lexing %q: token %d is %s, want %s(%s)", test.source, i, tokens[i].ToDisplayString(), want.kind.ToDisplayString(), want.characters)
+				}
+			}
+		})
+	}
+}
+
+func TestDollarWithoutMacroVariable(t *testing.T) {
+	_, err := NewLexerFromString("test", "$ x").CollectTokens()
+
+	var lexErr LexError
+
+	if !errors.As(err, &lexErr) || lexErr.Reason != UnknownCharacterLexErrorReason {
+		t.Errorf("got error %v, want an unknown character", err)
+	}
+}
+
+func TestQuotedIdentifiers(t *testing.T) {
+	tests := []struct {
+		source   string
+		name     string
+		operator OperatorKind
+	}{
+		{"`+`", "+", PlusOperatorKind},
+		{"`<<=`", "<<=", ShiftLeftEqualsOperatorKind},
+		{"`fn`", "fn", NotAnOperatorKind},
+		{"`with space`", "with space", NotAnOperatorKind},
+		{"`café`", "café", NotAnOperatorKind},
+	}
+
+	for _, test := range tests {
+		tokens, err := NewLexerFromString("test", test.source).CollectTokens()
+
+		if err != nil {
+			t.Errorf("lexing %q: %v", test.source, err)
+			continue
+		}
+
+		if len(tokens) != 1 || tokens[0].Kind() != QuotedIdentifierTokenKind {
+			t.Errorf("lexing %q: got %v, want one quoted identifier", test.source, tokens)
+			continue
+		}
+
+		tk := tokens[0]
+
+		if tk.Characters() != test.source || tk.IdentifierName() != test.name || tk.Keyword() != NotAKeywordKind || tk.Operator() != test.operator {
+			t.Errorf("lexing %q: got name %q and operator %s, want %q and %s", test.source, tk.IdentifierName(), tk.Operator().Spelling(), test.name, test.operator.Spelling())
+		}
+	}
+}
+
+func TestInvalidQuotedIdentifiers(t *testing.T) {
+	tests := []struct {
+		source string
+		reason LexErrorReason
+	}{
+		{"``", EmptyQuotedIdentifierLexErrorReason},
+		{"`+", UnterminatedQuotedIdentifierLexErrorReason},
+		{"`+\n`", UnterminatedQuotedIdentifierLexErrorReason},
+		{"`+\r\n`", UnterminatedQuotedIdentifierLexErrorReason},
+	}
+
+	for _, test := range tests {
+		_, err := NewLexerFromString("test", test.source).CollectTokens()
+
+		var lexErr LexError
+
+		if !errors.As(err, &lexErr) || lexErr.Reason != test.reason {
+			t.Errorf("lexing %q: got error %v, want %s", test.source, err, test.reason.ToDisplayString())
+		}
+	}
+}
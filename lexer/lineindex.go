@@ -0,0 +1,78 @@
+package lexer
+
+import (
+	"bytes"
+	"slices"
+)
+
+// Maps byte offsets in a source to lines and columns, for diagnostics and source maps.
+// Building the index is a single pass over the source, after which every lookup is a binary search.
+type LineIndex struct {
+	source     *Source
+	options    LexerOptions
+	lineStarts []uint64
+}
+
+func NewLineIndex(source *Source) *LineIndex {
+	return NewLineIndexWithOptions(source, DefaultLexerOptions())
+}
+
+// Creates an index whose columns match those of a lexer created with the same options.
+func NewLineIndexWithOptions(source *Source, options LexerOptions) *LineIndex {
+	i := new(LineIndex)
+
+	i.source = source
+	i.options = options
+	i.lineStarts = []uint64{0}
+
+	for offset, b := range source.Bytes() {
+		if b == '\n' {
+			i.lineStarts = append(i.lineStarts, uint64(offset+1))
+		}
+	}
+
+	return i
+}
+
+func (i *LineIndex) LineCount() uint32 {
+	return uint32(len(i.lineStarts))
+}
+
+// Returns the position of the byte offset in the source.
+// Columns count characters rather than bytes, and tabs are expanded, matching the positions produced by the lexer.
+func (i *LineIndex) Position(offset uint64) Position {
+	offset = min(offset, uint64(i.source.Len()))
+
+	// The line is the last one that starts at or before the offset
+	line, found := slices.BinarySearch(i.lineStarts, offset)
+
+	if !found {
+		line--
+	}
+
+	column := uint32(1)
+
+	for _, r := range string(i.source.Bytes()[i.lineStarts[line]:offset]) {
+		column = i.options.advanceColumn(column, r)
+	}
+
+	return InitPosition(uint32(line+1), column, offset, i.source.ID())
+}
+
+// Returns the text of the line, without its line break.
+// Lines are numbered from 1, and an empty string is returned for lines that do not exist.
+func (i *LineIndex) LineText(line uint32) string {
+	if line < 1 || line > i.LineCount() {
+		return ""
+	}
+
+	data := i.source.Bytes()
+	start := i.lineStarts[line-1]
+	end := uint64(len(data))
+
+	if line < i.LineCount() {
+		end = i.lineStarts[line] - 1
+	}
+
+	return string(bytes.TrimSuffix(data[start:end], []byte("\r")))
+}
@@ -0,0 +1,448 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"ljpprojects.org/sqopl/utils"
+)
+
+// Identifies the type suffix written after an integer literal, such as the i64 in 56i64.
+type IntegerSuffixKind uint8
+
+const (
+	NoIntegerSuffixKind IntegerSuffixKind = iota
+	I8IntegerSuffixKind
+	I16IntegerSuffixKind
+	I32IntegerSuffixKind
+	I64IntegerSuffixKind
+	I128IntegerSuffixKind
+	U8IntegerSuffixKind
+	U16IntegerSuffixKind
+	U32IntegerSuffixKind
+	U64IntegerSuffixKind
+	U128IntegerSuffixKind
+)
+
+var integerSuffixSpellings = map[IntegerSuffixKind]string{
+	I8IntegerSuffixKind:   "i8",
+	I16IntegerSuffixKind:  "i16",
+	I32IntegerSuffixKind:  "i32",
+	I64IntegerSuffixKind:  "i64",
+	I128IntegerSuffixKind: "i128",
+	U8IntegerSuffixKind:   "u8",
+	U16IntegerSuffixKind:  "u16",
+	U32IntegerSuffixKind:  "u32",
+	U64IntegerSuffixKind:  "u64",
+	U128IntegerSuffixKind: "u128",
+}
+
+// Returns the suffix spelled exactly as spelling, or NoIntegerSuffixKind if there is none.
+func IntegerSuffixKindFromSpelling(spelling string) IntegerSuffixKind {
+	for kind, s := range integerSuffixSpellings {
+		if s == spelling {
+			return kind
+		}
+	}
+
+	return NoIntegerSuffixKind
+}
+
+func (k IntegerSuffixKind) Spelling() string {
+	return integerSuffixSpellings[k]
+}
+
+// Reports whether the suffix makes the literal a signed integer.
+func (k IntegerSuffixKind) Signed() bool {
+	return k >= I8IntegerSuffixKind && k <= I128IntegerSuffixKind
+}
+
+// Returns the width in bits of the integer type the suffix names, or 0 for NoIntegerSuffixKind.
+func (k IntegerSuffixKind) Width() uint {
+	switch k {
+	case I8IntegerSuffixKind, U8IntegerSuffixKind:
+		return 8
+	case I16IntegerSuffixKind, U16IntegerSuffixKind:
+		return 16
+	case I32IntegerSuffixKind, U32IntegerSuffixKind:
+		return 32
+	case I64IntegerSuffixKind, U64IntegerSuffixKind:
+		return 64
+	case I128IntegerSuffixKind, U128IntegerSuffixKind:
+		return 128
+	}
+
+	return 0
+}
+
+// Reports whether value is in range for the integer type the suffix names.
+// Literals are lexed without a range check, so this is left to whoever knows the literal's type.
+// Every value fits NoIntegerSuffixKind.
+func (k IntegerSuffixKind) Fits(value *big.Int) bool {
+	if k == NoIntegerSuffixKind {
+		return true
+	}
+
+	if k.Signed() {
+		limit := new(big.Int).Lsh(big.NewInt(1), k.Width()-1)
+
+		return value.Cmp(new(big.Int).Neg(limit)) >= 0 && value.Cmp(limit) < 0
+	}
+
+	return value.Sign() >= 0 && value.BitLen() <= int(k.Width())
+}
+
+func (k IntegerSuffixKind) ToDisplayString() string {
+	if k == NoIntegerSuffixKind {
+		return "Suffix(None)"
+	}
+
+	return fmt.Sprintf("Suffix(%s)", k.Spelling())
+}
+
+// The bases whose literals start with a prefix, such as 0x.
+var prefixedNumericalBases = []LexerNumericalBase{
+	Base2LexerNumericalBase,
+	Base8LexerNumericalBase,
+	Base16LexerNumericalBase,
+}
+
+// Scans a number literal whose first digit, first, has already been read.
+//
+// A leading '0' may be followed by a base prefix letter (b, o or x), which must then be followed by at least one digit.
+// Otherwise, decimal literals cannot have leading zeros, so 0 is only ever written on its own or before a fraction or exponent.
+func (l *Lexer) scanNumber(startpos Position, first rune) (utils.Optional[Token], error) {
+	base := Base10LexerNumericalBase
+	spelling := string(first)
+
+	if first == '0' {
+		// Ranging over numericalBases would visit the bases in a random order, so check the prefixes in a fixed order
+		for _, b := range prefixedNumericalBases {
+			info := numericalBases[b]
+
+			if l.peekByteIs(0, info.prefix[1]) {
+				if _, err := l.readRune(false, false); err != nil {
+					return utils.NoneOptional[Token](), err
+				}
+
+				base = b
+				spelling = info.prefix
+
+				break
+			}
+		}
+	}
+
+	digits, err := l.readDigits(base)
+
+	if err != nil {
+		return utils.NoneOptional[Token](), err
+	}
+
+	if base != Base10LexerNumericalBase && digits == "" {
+		if err := l.rejectTrailingDigits(startpos, base); err != nil {
+			return utils.NoneOptional[Token](), err
+		}
+
+		return utils.NoneOptional[Token](), InitLexError(
+			InitLocation(startpos, l.currentPosition),
+			MalformedNumberLexErrorReason,
+			fmt.Sprintf("expected at least one base %d digit after '%s'", base.Radix(), spelling),
+		)
+	}
+
+	if base == Base10LexerNumericalBase && first == '0' && digits != "" {
+		return utils.NoneOptional[Token](), InitLexError(
+			InitLocation(startpos, l.currentPosition),
+			MalformedNumberLexErrorReason,
+			"decimal literals cannot have leading zeros, use the '0o' prefix for octal",
+		)
+	}
+
+	spelling += digits
+
+	mtail, err := l.scanFractionAndExponent(startpos, base)
+
+	if err != nil {
+		return utils.NoneOptional[Token](), err
+	}
+
+	if tail, err := mtail.Value(); err == nil {
+		return l.finishDecimalToken(startpos, spelling+tail, base)
+	}
+
+	return l.finishIntegerToken(startpos, spelling, base)
+}
+
+// Reports an invalid digit if the number that was just scanned is directly followed by a letter or digit, such as the 2 in 0b12.
+// The whole run of letters and digits is consumed so that lexing can carry on after it.
+func (l *Lexer) rejectTrailingDigits(startpos Position, base LexerNumericalBase) error {
+	run := ""
+
+	for {
+		mb, err := l.peekByte(0)
+
+		if err != nil {
+			return err
+		}
+
+		b, err := mb.Value()
+
+		if err != nil || !IsValidIdentPart(rune(b)) {
+			break
+		}
+
+		if _, err := l.readRune(false, false); err != nil {
+			return err
+		}
+
+		run += string(rune(b))
+	}
+
+	if run == "" {
+		return nil
+	}
+
+	return InitLexError(
+		InitLocation(startpos, l.currentPosition),
+		InvalidDigitLexErrorReason,
+		fmt.Sprintf("'%c' is not a valid digit in base %d", run[0], base.Radix()),
+	)
+}
+
+// Finishes scanning an integer literal whose digits, and base prefix if any, have already been read into spelling.
+// The token keeps the literal's full spelling, including any suffix; DecodeIntegerLiteral gives its value.
+func (l *Lexer) finishIntegerToken(startpos Position, spelling string, base LexerNumericalBase) (utils.Optional[Token], error) {
+	if err := checkDigitSeparators(InitLocation(startpos, l.currentPosition), spelling, base); err != nil {
+		return utils.NoneOptional[Token](), err
+	}
+
+	suffix, err := l.scanIntegerSuffix()
+
+	if err != nil {
+		return utils.NoneOptional[Token](), err
+	}
+
+	if err := l.rejectTrailingDigits(startpos, base); err != nil {
+		return utils.NoneOptional[Token](), err
+	}
+
+	return utils.SomeOptional(InitToken(IntegerTokenKind, spelling+suffix, InitLocation(startpos, l.currentPosition))), nil
+}
+
+// Finishes scanning a decimal literal whose whole spelling has already been read.
+func (l *Lexer) finishDecimalToken(startpos Position, spelling string, base LexerNumericalBase) (utils.Optional[Token], error) {
+	if err := checkDigitSeparators(InitLocation(startpos, l.currentPosition), spelling, base); err != nil {
+		return utils.NoneOptional[Token](), err
+	}
+
+	if err := l.rejectTrailingDigits(startpos, base); err != nil {
+		return utils.NoneOptional[Token](), err
+	}
+
+	return utils.SomeOptional(InitToken(DecimalTokenKind, spelling, InitLocation(startpos, l.currentPosition))), nil
+}
+
+// Reads digits valid in the given base, and digit separators, for as long as possible, stopping before the first character that is neither.
+func (l *Lexer) readDigits(base LexerNumericalBase) (string, error) {
+	digits := ""
+
+	for {
+		mb, err := l.peekByte(0)
+
+		if err != nil {
+			return "", err
+		}
+
+		b, err := mb.Value()
+
+		if err != nil || (b != '_' && !IsValidNumberPart(rune(b), base)) {
+			return digits, nil
+		}
+
+		if _, err := l.readRune(false, false); err != nil {
+			return "", err
+		}
+
+		digits += string(rune(b))
+	}
+}
+
+// Scans the fractional part and exponent that may follow the integer digits of a number, such as ".5e-9".
+// Hexadecimal numbers use a 'p' exponent (a power of 2), which is required if a fractional part is present.
+// Returns (None, nil) without consuming anything if the number is an integer.
+func (l *Lexer) scanFractionAndExponent(startpos Position, base LexerNumericalBase) (utils.Optional[string], error) {
+	exponentMarkers := numericalBases[base].exponentMarkers
+
+	if exponentMarkers == "" {
+		return utils.NoneOptional[string](), nil
+	}
+
+	str := ""
+	hasFraction := false
+	hasExponent := false
+
+	mb, err := l.peekBytes(2)
+
+	if err != nil {
+		return utils.NoneOptional[string](), err
+	}
+
+	// A '.' only starts a fraction if a digit follows it, so that member access on integers still works
+	if b, err := mb.Value(); err == nil && b[0] == '.' && IsValidNumberPart(rune(b[1]), base) {
+		if _, err := l.readRune(false, false); err != nil {
+			return utils.NoneOptional[string](), err
+		}
+
+		digits, err := l.readDigits(base)
+
+		if err != nil {
+			return utils.NoneOptional[string](), err
+		}
+
+		str += "." + digits
+		hasFraction = true
+	}
+
+	mmarker, err := l.peekByte(0)
+
+	if err != nil {
+		return utils.NoneOptional[string](), err
+	}
+
+	if marker, err := mmarker.Value(); err == nil && strings.ContainsRune(exponentMarkers, rune(marker)) {
+		digitOffset := 1
+
+		msign, err := l.peekByte(1)
+
+		if err != nil {
+			return utils.NoneOptional[string](), err
+		}
+
+		if sign, err := msign.Value(); err == nil && (sign == '+' || sign == '-') {
+			digitOffset = 2
+		}
+
+		mdigit, err := l.peekByte(digitOffset)
+
+		if err != nil {
+			return utils.NoneOptional[string](), err
+		}
+
+		// Exponents are always written in base 10
+		if digit, err := mdigit.Value(); err == nil && IsValidNumberPart(rune(digit), Base10LexerNumericalBase) {
+			for range digitOffset {
+				mp, err := l.readRune(false, false)
+
+				if err != nil {
+					return utils.NoneOptional[string](), err
+				}
+
+				p, _ := mp.Value()
+				str += string(p)
+			}
+
+			digits, err := l.readDigits(Base10LexerNumericalBase)
+
+			if err != nil {
+				return utils.NoneOptional[string](), err
+			}
+
+			str += digits
+			hasExponent = true
+		}
+	}
+
+	if base == Base16LexerNumericalBase && hasFraction && !hasExponent {
+		return utils.NoneOptional[string](), InitLexError(InitLocation(startpos, l.currentPosition), MalformedNumberLexErrorReason, "hexadecimal floating-point literals need a 'p' exponent")
+	}
+
+	if !hasFraction && !hasExponent {
+		return utils.NoneOptional[string](), nil
+	}
+
+	return utils.SomeOptional(str), nil
+}
+
+// Reads an integer suffix, such as u8, if one comes next.
+// Nothing is consumed unless a complete suffix is found, so that an invalid one is reported by Lexer.rejectTrailingDigits.
+func (l *Lexer) scanIntegerSuffix() (string, error) {
+	// The longest suffixes, such as i128, are 4 bytes long
+	b, err := l.reader.Peek(4)
+
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	for n := len(b); n >= 2; n-- {
+		if IntegerSuffixKindFromSpelling(string(b[:n])) == NoIntegerSuffixKind {
+			continue
+		}
+
+		for range n {
+			if _, err := l.readRune(false, false); err != nil {
+				return "", err
+			}
+		}
+
+		return string(b[:n]), nil
+	}
+
+	return "", nil
+}
+
+// Reports a malformed number if spelling has a digit separator that is not between two digits, such as in 1__000 or 0x_FF.
+func checkDigitSeparators(loc Location, spelling string, base LexerNumericalBase) error {
+	for i := range len(spelling) {
+		if spelling[i] != '_' {
+			continue
+		}
+
+		if i == 0 || i == len(spelling)-1 || !IsValidNumberPart(rune(spelling[i-1]), base) || !IsValidNumberPart(rune(spelling[i+1]), base) {
+			return InitLexError(loc, MalformedNumberLexErrorReason, "digit separators ('_') must be between two digits")
+		}
+	}
+
+	return nil
+}
+
+// Decodes the spelling of an integer literal, including any base prefix, digit separators and suffix.
+// Integer literals have arbitrary precision; use IntegerSuffixKind.Fits to check the value against its type.
+func DecodeIntegerLiteral(spelling string) (*big.Int, IntegerSuffixKind, error) {
+	suffix := NoIntegerSuffixKind
+
+	// Neither 'i' nor 'u' is a digit in any base, so the suffix starts at the first of them
+	if i := strings.IndexAny(spelling, "iu"); i != -1 {
+		suffix = IntegerSuffixKindFromSpelling(spelling[i:])
+
+		if suffix == NoIntegerSuffixKind {
+			return nil, NoIntegerSuffixKind, fmt.Errorf("Integer literal %s has an unknown suffix %q", spelling, spelling[i:])
+		}
+
+		spelling = spelling[:i]
+	}
+
+	radix := 10
+	digits := spelling
+
+	if len(spelling) > 2 && spelling[0] == '0' {
+		switch spelling[1] {
+		case 'b':
+			radix, digits = 2, spelling[2:]
+		case 'o':
+			radix, digits = 8, spelling[2:]
+		case 'x':
+			radix, digits = 16, spelling[2:]
+		}
+	}
+
+	value, ok := new(big.Int).SetString(strings.ReplaceAll(digits, "_", ""), radix)
+
+	if !ok {
+		return nil, NoIntegerSuffixKind, fmt.Errorf("Integer literal %s is not a valid base %d number", spelling, radix)
+	}
+
+	return value, suffix, nil
+}
@@ -0,0 +1,111 @@
+package lexer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNumberLiterals(t *testing.T) {
+	type expectedToken struct {
+		kind       TokenKind
+		characters string
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		tokens []expectedToken
+	}{
+		{"zero", "0", []expectedToken{{IntegerTokenKind, "0"}}},
+		{"zero before identifier", "0 x", []expectedToken{{IntegerTokenKind, "0"}, {IdentifierTokenKind, "x"}}},
+		{"decimal", "1234", []expectedToken{{IntegerTokenKind, "1234"}}},
+		{"decimal with separators and suffix", "1_000u8", []expectedToken{{IntegerTokenKind, "1_000u8"}}},
+		{"binary", "0b1010", []expectedToken{{IntegerTokenKind, "0b1010"}}},
+		{"octal", "0o755", []expectedToken{{IntegerTokenKind, "0o755"}}},
+		{"hexadecimal", "0xbeef", []expectedToken{{IntegerTokenKind, "0xbeef"}}},
+		{"hexadecimal starting with b", "0xb1", []expectedToken{{IntegerTokenKind, "0xb1"}}},
+		{"hexadecimal starting with o", "0x0b", []expectedToken{{IntegerTokenKind, "0x0b"}}},
+		{"decimal exponent", "1e-9", []expectedToken{{DecimalTokenKind, "1e-9"}}},
+		{"decimal fraction", "3.25", []expectedToken{{DecimalTokenKind, "3.25"}}},
+		{"hexadecimal exponent", "0x1.8p3", []expectedToken{{DecimalTokenKind, "0x1.8p3"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := NewLexerFromString("test", test.source).CollectTokens()
+
+			if err != nil {
+				t.Fatalf("lexing %q: %v", test.source, err)
+			}
+
+			if len(tokens) != len(test.tokens) {
+				t.Fatalf("lexing %q: got %d tokens, want %d", test.source, len(tokens), len(test.tokens))
+			}
+
+			for i, want := range test.tokens {
+				if tokens[i].Kind() != want.kind || tokens[i].Characters() != want.characters {
+					t.Errorf("lexing %q: token %d is %s, want %s(%s)", test.source, i, tokens[i].ToDisplayString(), want.kind.ToDisplayString(), want.characters)
+				}
+			}
+		})
+	}
+}
+
+func TestMalformedNumberLiterals(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		reason LexErrorReason
+	}{
+		{"prefix without digits", "0x;", MalformedNumberLexErrorReason},
+		{"leading zero", "08", MalformedNumberLexErrorReason},
+		{"invalid binary digit", "0b12", InvalidDigitLexErrorReason},
+		{"invalid octal digit", "0o8", InvalidDigitLexErrorReason},
+		{"separator after prefix", "0x_F", MalformedNumberLexErrorReason},
+		{"trailing separator", "1_", MalformedNumberLexErrorReason},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewLexerFromString("test", test.source).CollectTokens()
+
+			var lexErr LexError
+
+			if !errors.As(err, &lexErr) {
+				t.Fatalf("lexing %q: got error %v, want a LexError", test.source, err)
+			}
+
+			if lexErr.Reason != test.reason {
+				t.Errorf("lexing %q: got %s, want %s", test.source, lexErr.Reason.ToDisplayString(), test.reason.ToDisplayString())
+			}
+		})
+	}
+}
+
+func TestDecodeIntegerLiteral(t *testing.T) {
+	tests := []struct {
+		spelling string
+		value    int64
+		suffix   IntegerSuffixKind
+	}{
+		{"0", 0, NoIntegerSuffixKind},
+		{"1_000u8", 1000, IntegerSuffixKindFromSpelling("u8")},
+		{"0b101", 5, NoIntegerSuffixKind},
+		{"0o17", 15, NoIntegerSuffixKind},
+		{"0xb1", 177, NoIntegerSuffixKind},
+		{"0xbeef", 48879, NoIntegerSuffixKind},
+	}
+
+	for _, test := range tests {
+		value, suffix, err := DecodeIntegerLiteral(test.spelling)
+
+		if err != nil {
+			t.Errorf("decoding %q: %v", test.spelling, err)
+			continue
+		}
+
+		if value.Int64() != test.value || suffix != test.suffix {
+			t.Errorf("decoding %q: got %s %s, want %d %s", test.spelling, value, suffix.ToDisplayString(), test.value, test.suffix.ToDisplayString())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package lexer
+
+// Controls how a Lexer reads its source.
+// Use DefaultLexerOptions as a starting point rather than the zero value.
+type LexerOptions struct {
+	// Tabs move the column to the next multiple of TabWidth (plus one, as columns count from 1).
+	// A TabWidth of 0 or 1 counts each tab as a single column.
+	TabWidth uint32
+
+	// When set, errors in the source text produce ErrorTokenKind tokens and are collected by Lexer.Errors,
+	// so that lexing carries on and several problems can be reported in one pass.
+	// Otherwise, the first LexError is returned from Lexer.NextToken.
+	ErrorTokens bool
+
+	// When set, comments are kept as trivia on the tokens around them (see Token.LeadingTrivia and Token.TrailingTrivia),
+	// instead of being thrown away.
+	KeepComments bool
+
+	// When set, whitespace and line breaks are kept as trivia in the same way as comments.
+	// Together with KeepComments, this lets the source be reproduced exactly from its tokens.
+	KeepWhitespace bool
+}
+
+func DefaultLexerOptions() LexerOptions {
+	return LexerOptions{
+		TabWidth: 4,
+	}
+}
+
+// Returns the column after a character r that starts at the given column.
+// Line breaks are not handled here, as they reset the column rather than advancing it.
+func (o LexerOptions) advanceColumn(column uint32, r rune) uint32 {
+	if r == '\t' && o.TabWidth > 1 {
+		return ((column-1)/o.TabWidth+1)*o.TabWidth + 1
+	}
+
+	return column + 1
+}
@@ -0,0 +1,66 @@
+package lexer
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Uniquely identifies a Source within a run of the program, so that positions can be traced back to the source they are in.
+// The zero SourceID never belongs to a source.
+type SourceID uint32
+
+var lastSourceID atomic.Uint32
+
+// A Source holds the complete contents of a piece of SQOPL code, along with a name used to label it in diagnostics.
+// Lexers never touch the underlying reader or file again once a Source has been created, which keeps cloning and peeking cheap.
+type Source struct {
+	id   SourceID
+	name string
+	data []byte
+}
+
+// Reads everything from r into a new Source labelled with name.
+func NewSourceFromReader(name string, r io.Reader) (*Source, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSourceFromBytes(name, data), nil
+}
+
+func NewSourceFromBytes(name string, data []byte) *Source {
+	s := new(Source)
+
+	s.id = SourceID(lastSourceID.Add(1))
+	s.name = name
+	s.data = data
+
+	return s
+}
+
+func NewSourceFromString(name string, str string) *Source {
+	return NewSourceFromBytes(name, []byte(str))
+}
+
+func (s *Source) ID() SourceID {
+	return s.id
+}
+
+func (s *Source) Name() string {
+	return s.name
+}
+
+func (s *Source) Bytes() []byte {
+	return s.data
+}
+
+func (s *Source) Len() int {
+	return len(s.data)
+}
+
+// Returns the text that loc spans, which must be a location in this source.
+func (s *Source) Text(loc Location) string {
+	return string(s.data[loc.Start.offset:loc.End.offset])
+}
@@ -2,8 +2,8 @@ package lexer
 
 import (
 	"fmt"
-	"slices"
 
+	"golang.org/x/text/unicode/norm"
 	"ljpprojects.org/sqopl/utils"
 )
 
@@ -13,31 +13,355 @@ func InitLocation(start Position, end Position) Location {
 	return Location(utils.InitRange(start, end))
 }
 
+// Returns the ID of the source that the location is in.
+func (l Location) SourceID() SourceID {
+	return l.Start.source
+}
+
+// Returns the number of bytes that the location spans.
+func (l Location) Len() uint64 {
+	return l.End.offset - l.Start.offset
+}
+
+// A set of characters that each start a token of a particular kind.
 type TokenGroup []rune
 
 var (
 	TokenOperatorGroup  TokenGroup = TokenGroup("~!^&*-+=|/.?<>%")
 	TokenSeparatorGroup TokenGroup = TokenGroup(";:,")
 	TokenGroupingGroup  TokenGroup = TokenGroup("([{}])")
+)
+
+type TokenKind uint8
+
+const (
+	IdentifierTokenKind TokenKind = iota
+	KeywordTokenKind
+	OperatorTokenKind
+	SeparatorTokenKind
+	GroupingTokenKind
+	StringTokenKind
+	IntegerTokenKind
+	DecimalTokenKind
+	CharacterTokenKind
+	ErrorTokenKind
+	// A macro name with its '#' sigil, as in #repeat.
+	MacroNameTokenKind
+	// A macro variable with its '$' sigil, as in $block.
+	MacroVariableTokenKind
+	// The '$' that opens a repetition in a macro pattern, as in $($e AnyExpression),+.
+	MacroRepetitionTokenKind
+	// An identifier quoted in backticks, which may be spelt like an operator or keyword, as in `+` or `fn`.
+	// The characters include the backticks.
+	QuotedIdentifierTokenKind
+)
+
+func (k TokenKind) ToDisplayString() string {
+	switch k {
+	case IdentifierTokenKind:
+		return "Identifier"
+	case KeywordTokenKind:
+		return "Keyword"
+	case OperatorTokenKind:
+		return "Operator"
+	case SeparatorTokenKind:
+		return "Separator"
+	case GroupingTokenKind:
+		return "Grouping"
+	case StringTokenKind:
+		return "String"
+	case IntegerTokenKind:
+		return "Integer"
+	case DecimalTokenKind:
+		return "Decimal"
+	case CharacterTokenKind:
+		return "Character"
+	case ErrorTokenKind:
+		return "Error"
+	case MacroNameTokenKind:
+		return "MacroName"
+	case MacroVariableTokenKind:
+		return "MacroVariable"
+	case MacroRepetitionTokenKind:
+		return "MacroRepetition"
+	case QuotedIdentifierTokenKind:
+		return "QuotedIdentifier"
+	}
+
+	return "Unknown"
+}
+
+// Identifies which reserved word a keyword token is.
+// Keywords can never be used as identifiers.
+type KeywordKind uint8
 
-	TokenIdentifierGroup TokenGroup = TokenGroup{}
-	TokenStringGroup     TokenGroup = TokenGroup{}
-	TokenIntegerGroup    TokenGroup = TokenGroup{}
-	TokenDecimalGroup    TokenGroup = TokenGroup{}
+const (
+	NotAKeywordKind KeywordKind = iota
+	FnKeywordKind
+	LetKeywordKind
+	VarKeywordKind
+	ConstKeywordKind
+	StructKeywordKind
+	ClassKeywordKind
+	EnumKeywordKind
+	InterfaceKeywordKind
+	NamespaceKeywordKind
+	ImportKeywordKind
+	ExternKeywordKind
+	InternalKeywordKind
+	MacroKeywordKind
+	NewKeywordKind
+	IfKeywordKind
+	ElseKeywordKind
+	GuardKeywordKind
+	SwitchKeywordKind
+	MatchKeywordKind
+	WhenKeywordKind
+	WhereKeywordKind
+	ForKeywordKind
+	InKeywordKind
+	WhileKeywordKind
+	ForeverKeywordKind
+	DeferKeywordKind
+	ReturnKeywordKind
+	AsKeywordKind
+	IsKeywordKind
+	MutKeywordKind
+	EscapingKeywordKind
+	TableKeywordKind
 )
 
+var keywordSpellings = map[KeywordKind]string{
+	FnKeywordKind:        "fn",
+	LetKeywordKind:       "let",
+	VarKeywordKind:       "var",
+	ConstKeywordKind:     "const",
+	StructKeywordKind:    "struct",
+	ClassKeywordKind:     "class",
+	EnumKeywordKind:      "enum",
+	InterfaceKeywordKind: "interface",
+	NamespaceKeywordKind: "namespace",
+	ImportKeywordKind:    "import",
+	ExternKeywordKind:    "extern",
+	InternalKeywordKind:  "internal",
+	MacroKeywordKind:     "macro",
+	NewKeywordKind:       "new",
+	IfKeywordKind:        "if",
+	ElseKeywordKind:      "else",
+	GuardKeywordKind:     "guard",
+	SwitchKeywordKind:    "switch",
+	MatchKeywordKind:     "match",
+	WhenKeywordKind:      "when",
+	WhereKeywordKind:     "where",
+	ForKeywordKind:       "for",
+	InKeywordKind:        "in",
+	WhileKeywordKind:     "while",
+	ForeverKeywordKind:   "forever",
+	DeferKeywordKind:     "defer",
+	ReturnKeywordKind:    "return",
+	AsKeywordKind:        "as",
+	IsKeywordKind:        "is",
+	MutKeywordKind:       "mut",
+	EscapingKeywordKind:  "escaping",
+	TableKeywordKind:     "table",
+}
+
+// Returns the keyword spelled exactly as spelling, or NotAKeywordKind if there is none.
+func KeywordKindFromSpelling(spelling string) KeywordKind {
+	for kind, s := range keywordSpellings {
+		if s == spelling {
+			return kind
+		}
+	}
+
+	return NotAKeywordKind
+}
+
+// Returns the keyword that an identifier spelt as in the source is, or NotAKeywordKind.
+// Spellings are compared in Unicode Normalization Form C, the same as Token.IdentifierName.
+func keywordKindOfIdentifier(spelling string) KeywordKind {
+	return KeywordKindFromSpelling(norm.NFC.String(spelling))
+}
+
+func (k KeywordKind) Spelling() string {
+	return keywordSpellings[k]
+}
+
+func (k KeywordKind) ToDisplayString() string {
+	if k == NotAKeywordKind {
+		return "Keyword(None)"
+	}
+
+	return fmt.Sprintf("Keyword(%s)", k.Spelling())
+}
+
+// Identifies which operator an operator token is.
+// Operators are lexed with maximal munch, so "->" is always ArrowOperatorKind, never '-' followed by '>'.
+type OperatorKind uint8
+
+const (
+	NotAnOperatorKind OperatorKind = iota
+	TildeOperatorKind
+	BangOperatorKind
+	CaretOperatorKind
+	AmpersandOperatorKind
+	AsteriskOperatorKind
+	MinusOperatorKind
+	PlusOperatorKind
+	EqualsOperatorKind
+	PipeOperatorKind
+	SlashOperatorKind
+	DotOperatorKind
+	QuestionOperatorKind
+	LessThanOperatorKind
+	GreaterThanOperatorKind
+	PercentOperatorKind
+	ArrowOperatorKind
+	EqualsEqualsOperatorKind
+	NotEqualsOperatorKind
+	LessThanEqualsOperatorKind
+	GreaterThanEqualsOperatorKind
+	AndAndOperatorKind
+	OrOrOperatorKind
+	NullCoalesceOperatorKind
+	OptionalChainOperatorKind
+	IncrementOperatorKind
+	DecrementOperatorKind
+	PlusEqualsOperatorKind
+	MinusEqualsOperatorKind
+	AsteriskEqualsOperatorKind
+	SlashEqualsOperatorKind
+	PercentEqualsOperatorKind
+	AmpersandEqualsOperatorKind
+	PipeEqualsOperatorKind
+	CaretEqualsOperatorKind
+	ShiftLeftOperatorKind
+	ShiftRightOperatorKind
+	ShiftLeftEqualsOperatorKind
+	ShiftRightEqualsOperatorKind
+	RangeOperatorKind
+	EllipsisOperatorKind
+	OptionalCastOperatorKind
+)
+
+var operatorSpellings = map[OperatorKind]string{
+	TildeOperatorKind:             "~",
+	BangOperatorKind:              "!",
+	CaretOperatorKind:             "^",
+	AmpersandOperatorKind:         "&",
+	AsteriskOperatorKind:          "*",
+	MinusOperatorKind:             "-",
+	PlusOperatorKind:              "+",
+	EqualsOperatorKind:            "=",
+	PipeOperatorKind:              "|",
+	SlashOperatorKind:             "/",
+	DotOperatorKind:               ".",
+	QuestionOperatorKind:          "?",
+	LessThanOperatorKind:          "<",
+	GreaterThanOperatorKind:       ">",
+	PercentOperatorKind:           "%",
+	ArrowOperatorKind:             "->",
+	EqualsEqualsOperatorKind:      "==",
+	NotEqualsOperatorKind:         "!=",
+	LessThanEqualsOperatorKind:    "<=",
+	GreaterThanEqualsOperatorKind: ">=",
+	AndAndOperatorKind:            "&&",
+	OrOrOperatorKind:              "||",
+	NullCoalesceOperatorKind:      "??",
+	OptionalChainOperatorKind:     "?.",
+	IncrementOperatorKind:         "++",
+	DecrementOperatorKind:         "--",
+	PlusEqualsOperatorKind:        "+=",
+	MinusEqualsOperatorKind:       "-=",
+	AsteriskEqualsOperatorKind:    "*=",
+	SlashEqualsOperatorKind:       "/=",
+	PercentEqualsOperatorKind:     "%=",
+	AmpersandEqualsOperatorKind:   "&=",
+	PipeEqualsOperatorKind:        "|=",
+	CaretEqualsOperatorKind:       "^=",
+	ShiftLeftOperatorKind:         "<<",
+	ShiftRightOperatorKind:        ">>",
+	ShiftLeftEqualsOperatorKind:   "<<=",
+	ShiftRightEqualsOperatorKind:  ">>=",
+	RangeOperatorKind:             "..",
+	EllipsisOperatorKind:          "...",
+	OptionalCastOperatorKind:      "as?",
+}
+
+// The length in bytes of the longest spelling in operatorSpellings that is made only of TokenOperatorGroup characters.
+const maxOperatorLength = 3
+
+// Returns the operator spelled exactly as spelling, or NotAnOperatorKind if there is none.
+func OperatorKindFromSpelling(spelling string) OperatorKind {
+	for kind, s := range operatorSpellings {
+		if s == spelling {
+			return kind
+		}
+	}
+
+	return NotAnOperatorKind
+}
+
+func (k OperatorKind) Spelling() string {
+	return operatorSpellings[k]
+}
+
+func (k OperatorKind) ToDisplayString() string {
+	if k == NotAnOperatorKind {
+		return "Operator(None)"
+	}
+
+	return fmt.Sprintf("Operator(%s)", k.Spelling())
+}
+
 type Token struct {
-	group      *TokenGroup
+	kind       TokenKind
 	characters string
 	loc        Location
+	operator   OperatorKind
+	keyword    KeywordKind
+
+	leadingTrivia  []Trivia
+	trailingTrivia []Trivia
 }
 
 func (t Token) Characters() string {
 	return t.characters
 }
 
-func (t Token) Group() *TokenGroup {
-	return t.group
+func (t Token) Kind() TokenKind {
+	return t.kind
+}
+
+// Returns which operator the token is, or NotAnOperatorKind if it is not an operator token.
+// Quoted identifiers spelt like an operator, such as `+`, also report that operator.
+func (t Token) Operator() OperatorKind {
+	return t.operator
+}
+
+// Returns the name an identifier token refers to, which for quoted identifiers excludes the backticks.
+// Unlike Token.Characters, the name is in Unicode Normalization Form C, so names which look the same are spelt the same.
+func (t Token) IdentifierName() string {
+	if t.kind == QuotedIdentifierTokenKind {
+		return norm.NFC.String(t.characters[1 : len(t.characters)-1])
+	}
+
+	return norm.NFC.String(t.characters)
+}
+
+// Returns which keyword the token is, or NotAKeywordKind if it is not a keyword token.
+func (t Token) Keyword() KeywordKind {
+	return t.keyword
+}
+
+// Returns the trivia between the previous token's trailing trivia and this token.
+func (t Token) LeadingTrivia() []Trivia {
+	return t.leadingTrivia
+}
+
+// Returns the trivia after this token up to, but not including, the end of its line.
+func (t Token) TrailingTrivia() []Trivia {
+	return t.trailingTrivia
 }
 
 func (t Token) Startpos() Position {
@@ -51,7 +375,7 @@ func (t Token) Endpos() Position {
 func (t Token) ToDisplayString() string {
 	return fmt.Sprintf(
 		"%s(%s) @ (%d:%d)-(%d:%d)",
-		t.group.ToDisplayString(),
+		t.kind.ToDisplayString(),
 		t.characters,
 		t.loc.Start.line,
 		t.loc.Start.column,
@@ -60,30 +384,49 @@ func (t Token) ToDisplayString() string {
 	)
 }
 
-func (g *TokenGroup) ToDisplayString() string {
-	if slices.Compare(*g, TokenOperatorGroup) == 0 {
-		return "Operators"
-	} else if slices.Compare(*g, TokenSeparatorGroup) == 0 {
-		return "Separators"
-	} else if slices.Compare(*g, TokenGroupingGroup) == 0 {
-		return "Grouping"
-	} else if slices.Compare(*g, TokenIdentifierGroup) == 0 {
-		return "Identifiers"
-	} else if slices.Compare(*g, TokenStringGroup) == 0 {
-		return "Strings"
-	} else if slices.Compare(*g, TokenIntegerGroup) == 0 {
-		return "Integers"
-	} else if slices.Compare(*g, TokenDecimalGroup) == 0 {
-		return "Decimals"
-	} else {
-		return "Unknown"
+func InitToken(kind TokenKind, characters string, loc Location) Token {
+	operator := NotAnOperatorKind
+	keyword := NotAKeywordKind
+
+	switch kind {
+	case OperatorTokenKind:
+		operator = OperatorKindFromSpelling(characters)
+	case KeywordTokenKind:
+		keyword = keywordKindOfIdentifier(characters)
+	case QuotedIdentifierTokenKind:
+		operator = OperatorKindFromSpelling(characters[1 : len(characters)-1])
 	}
-}
 
-func InitToken(group *TokenGroup, characters string, loc Location) Token {
 	return Token{
-		group,
-		characters,
-		loc,
+		kind:       kind,
+		characters: characters,
+		loc:        loc,
+		operator:   operator,
+		keyword:    keyword,
 	}
 }
+
+// Splits an operator token into the operators spelt by its first n bytes and by the rest of it, such as >= into > and =.
+// This is for parsers, where one operator read by the lexer may really be two, such as the ">>" closing two lists of generics.
+// The first token keeps the leading trivia, and the second keeps the trailing trivia.
+func (t Token) SplitOperator(n int) (Token, Token) {
+	middle := t.loc.Start
+	middle.column += uint32(n)
+	middle.offset += uint64(n)
+
+	first := InitToken(OperatorTokenKind, t.characters[:n], InitLocation(t.loc.Start, middle))
+	first.leadingTrivia = t.leadingTrivia
+
+	rest := InitToken(OperatorTokenKind, t.characters[n:], InitLocation(middle, t.loc.End))
+	rest.trailingTrivia = t.trailingTrivia
+
+	return first, rest
+}
+
+func InitOperatorToken(operator OperatorKind, loc Location) Token {
+	return InitToken(OperatorTokenKind, operator.Spelling(), loc)
+}
+
+func InitKeywordToken(keyword KeywordKind, loc Location) Token {
+	return InitToken(KeywordTokenKind, keyword.Spelling(), loc)
+}
@@ -0,0 +1,217 @@
+package lexer
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ljpprojects.org/sqopl/utils"
+)
+
+type TriviaKind uint8
+
+const (
+	WhitespaceTriviaKind TriviaKind = iota
+	CommentTriviaKind
+	// A comment starting with "##", which documents the code after it.
+	DocCommentTriviaKind
+)
+
+func (k TriviaKind) ToDisplayString() string {
+	switch k {
+	case WhitespaceTriviaKind:
+		return "Whitespace"
+	case CommentTriviaKind:
+		return "Comment"
+	case DocCommentTriviaKind:
+		return "Doc Comment"
+	}
+
+	return "Unknown"
+}
+
+// Source text that has no meaning to the parser, but is kept so that tools such as formatters can reproduce it.
+// Trivia is only produced when LexerOptions.KeepComments or LexerOptions.KeepWhitespace is set.
+type Trivia struct {
+	kind       TriviaKind
+	characters string
+	loc        Location
+}
+
+func InitTrivia(kind TriviaKind, characters string, loc Location) Trivia {
+	return Trivia{
+		kind,
+		characters,
+		loc,
+	}
+}
+
+func (t Trivia) Kind() TriviaKind {
+	return t.kind
+}
+
+// Returns the exact source text of the trivia.
+// Comments include their leading '#' characters but not the line break after them.
+func (t Trivia) Characters() string {
+	return t.characters
+}
+
+func (t Trivia) Location() Location {
+	return t.loc
+}
+
+// Returns the text of a doc comment without its "##" and the single space after it, if there is one.
+func (t Trivia) DocText() string {
+	return strings.TrimPrefix(strings.TrimLeft(t.characters, "#"), " ")
+}
+
+// Returns the doc comment lines directly before the token, joined with line breaks.
+// Returns an empty string if the token has no doc comment.
+func (t Token) DocComment() string {
+	var lines []string
+
+	for _, trivia := range t.leadingTrivia {
+		switch trivia.kind {
+		case DocCommentTriviaKind:
+			lines = append(lines, trivia.DocText())
+		case CommentTriviaKind:
+			// An ordinary comment separates the doc comment from the token
+			lines = nil
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Reports whether the reader is at the start of a comment.
+// A '#' directly followed by an identifier, as in #repeat, is a macro name rather than a comment.
+func (l *Lexer) atComment() bool {
+	if !l.peekByteIs(0, '#') {
+		return false
+	}
+
+	mr, err := l.peekRuneAt(1)
+
+	if err != nil {
+		return true
+	}
+
+	r, err := mr.Value()
+
+	return err != nil || !IsValidIdentStart(r)
+}
+
+// Returns the next character without consuming it.
+// Returns (None, nil) when EOF
+func (l *Lexer) peekRune() (utils.Optional[rune], error) {
+	return l.peekRuneAt(0)
+}
+
+// Returns the character starting n bytes ahead of the reader without consuming anything.
+// Returns (None, nil) if the source ends first.
+func (l *Lexer) peekRuneAt(n int) (utils.Optional[rune], error) {
+	b, err := l.reader.Peek(n + utf8.UTFMax)
+
+	if len(b) <= n {
+		if err != nil && err != io.EOF {
+			return utils.NoneOptional[rune](), err
+		}
+
+		return utils.NoneOptional[rune](), nil
+	}
+
+	r, _ := utf8.DecodeRune(b[n:])
+
+	return utils.SomeOptional(r), nil
+}
+
+// Reads a single character, including whitespace and line breaks, keeping the position up to date.
+func (l *Lexer) readAnyRune() (utils.Optional[rune], error) {
+	mr, err := l.readRune(false, false)
+
+	if err != nil {
+		return utils.NoneOptional[rune](), err
+	}
+
+	if r, err := mr.Value(); err == nil && r == '\n' {
+		l.currentPosition.line++
+		l.currentPosition.column = 1
+	}
+
+	return mr, nil
+}
+
+// Reads whitespace and comments until the next token.
+// If stopAtLineBreak is set, reading also stops before the next line break, which is how trailing trivia is found.
+// Whitespace and comments are only returned if the lexer is set to keep them.
+func (l *Lexer) scanTrivia(stopAtLineBreak bool) ([]Trivia, error) {
+	var trivia []Trivia
+
+	for {
+		mr, err := l.peekRune()
+
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := mr.Value()
+
+		if err != nil || (stopAtLineBreak && r == '\n') {
+			return trivia, nil
+		}
+
+		startpos := l.currentPosition
+		text := ""
+		kind := WhitespaceTriviaKind
+
+		if unicode.IsSpace(r) {
+			for unicode.IsSpace(r) && !(stopAtLineBreak && r == '\n') {
+				if _, err := l.readAnyRune(); err != nil {
+					return nil, err
+				}
+
+				text += string(r)
+
+				mr, err = l.peekRune()
+
+				if err != nil {
+					return nil, err
+				}
+
+				if r, err = mr.Value(); err != nil {
+					break
+				}
+			}
+		} else if l.atComment() {
+			kind = CommentTriviaKind
+
+			if l.peekByteIs(1, '#') {
+				kind = DocCommentTriviaKind
+			}
+
+			// The line break, including the '\r' of a "\r\n", is whitespace rather than part of the comment
+			for err == nil && r != '\n' && !(r == '\r' && l.peekByteIs(1, '\n')) {
+				if _, err := l.readAnyRune(); err != nil {
+					return nil, err
+				}
+
+				text += string(r)
+
+				mr, err = l.peekRune()
+
+				if err != nil {
+					return nil, err
+				}
+
+				r, err = mr.Value()
+			}
+		} else {
+			return trivia, nil
+		}
+
+		if (kind == WhitespaceTriviaKind && l.options.KeepWhitespace) || (kind != WhitespaceTriviaKind && l.options.KeepComments) {
+			trivia = append(trivia, InitTrivia(kind, text, InitLocation(startpos, l.currentPosition)))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+// Lexes source keeping all trivia, and returns the source rebuilt from the tokens and their trivia.
+func rebuildSource(t *testing.T, source string) string {
+	t.Helper()
+
+	options := DefaultLexerOptions()
+	options.KeepComments = true
+	options.KeepWhitespace = true
+
+	l := NewLexerWithOptions(NewSourceFromString("test", source), options)
+	tokens, err := l.CollectTokens()
+
+	if err != nil {
+		t.Fatalf("lexing %q: %v", source, err)
+	}
+
+	var sb strings.Builder
+
+	writeTrivia := func(trivia []Trivia) {
+		for _, item := range trivia {
+			sb.WriteString(item.Characters())
+		}
+	}
+
+	for _, tk := range tokens {
+		writeTrivia(tk.LeadingTrivia())
+		sb.WriteString(tk.Characters())
+		writeTrivia(tk.TrailingTrivia())
+	}
+
+	writeTrivia(l.EndOfFileTrivia())
+
+	return sb.String()
+}
+
+func TestTriviaRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"empty", ""},
+		{"only whitespace", " \t\n  \n"},
+		{"only a comment", "# nothing here"},
+		{"leading and trailing", "  # leading\nlet a = 1; # trailing\n"},
+		{"end of file trivia", "let a = 1;\n\n# the end\n   "},
+		{"doc comments", "## Adds two numbers.\n## Overflow wraps.\nfn Add(a Integer32, b Integer32) -> Integer32 { a + b }\n"},
+		{"consecutive comments", "# one\n## two\n#three\n\tlet a = 1;"},
+		{"CRLF", "# comment\r\nlet a = 1; # trailing\r\n\r\nlet b = 2;\r\n"},
+		{"CRLF multiline string", "let s = \"\"\"\r\n    text\r\n    \"\"\";\r\n"},
+		{"tabs and spaces", "\tfn\t Main  {\n\t\treturn;\n\t}"},
+		{"macro name is not a comment", "#repeat(x) # comment\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rebuildSource(t, test.source); got != test.source {
+				t.Errorf("rebuilt %q, want %q", got, test.source)
+			}
+		})
+	}
+}
+
+func TestTrailingTriviaStopsAtLineBreak(t *testing.T) {
+	options := DefaultLexerOptions()
+	options.KeepComments = true
+
+	tokens, err := NewLexerWithOptions(NewSourceFromString("test", "a # after a\r\nb"), options).CollectTokens()
+
+	if err != nil {
+		t.Fatalf("lexing: %v", err)
+	}
+
+	if len(tokens) != 2 {
+		t.Fatalf("got %d tokens, want 2", len(tokens))
+	}
+
+	trailing := tokens[0].TrailingTrivia()
+
+	if len(trailing) != 1 || trailing[0].Characters() != "# after a" {
+		t.Errorf("got trailing trivia %v, want the comment without its line break", trailing)
+	}
+
+	if len(tokens[1].LeadingTrivia()) != 0 {
+		t.Errorf("got leading trivia %v on b, want none", tokens[1].LeadingTrivia())
+	}
+}
+
+func TestDocComment(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"single line", "## Adds two numbers.\nfn", "Adds two numbers."},
+		{"several lines", "## Adds two numbers.\n##\n##   Indented.\nfn", "Adds two numbers.\n\n  Indented."},
+		{"no space after hashes", "##Tight.\nfn", "Tight."},
+		{"ordinary comment", "# Not documentation.\nfn", ""},
+		{"separated by an ordinary comment", "## Dropped.\n# Note\n## Kept.\nfn", "Kept."},
+		{"CRLF", "## First.\r\n## Second.\r\nfn", "First.\nSecond."},
+		{"none", "fn", ""},
+	}
+
+	options := DefaultLexerOptions()
+	options.KeepComments = true
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := NewLexerWithOptions(NewSourceFromString("test", test.source), options).CollectTokens()
+
+			if err != nil {
+				t.Fatalf("lexing %q: %v", test.source, err)
+			}
+
+			if got := tokens[0].DocComment(); got != test.want {
+				t.Errorf("got doc comment %q, want %q", got, test.want)
+			}
+		})
+	}
+}
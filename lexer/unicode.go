@@ -0,0 +1,107 @@
+package lexer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Reports whether r can start an identifier.
+// This follows XID_Start from Unicode Standard Annex #31, with '_' also allowed.
+func IsValidIdentStart(r rune) bool {
+	if r == '_' {
+		return true
+	}
+
+	if unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space) {
+		return false
+	}
+
+	return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_ID_Start)
+}
+
+// Reports whether r can appear in an identifier after its first character.
+// This follows XID_Continue from Unicode Standard Annex #31.
+func IsValidIdentPart(r rune) bool {
+	if IsValidIdentStart(r) {
+		return true
+	}
+
+	if unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space) {
+		return false
+	}
+
+	return unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue)
+}
+
+// Characters from other scripts that are easily mistaken for an ASCII letter or digit, mapped to that character.
+// This is a small subset of the Unicode confusables data (Unicode Technical Standard #39) covering the most common cases.
+var asciiConfusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'B', 'е': 'e', 'к': 'k', 'м': 'M', 'н': 'H', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 'T',
+	'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ј': 'j', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T',
+	'Х': 'X', 'Ѕ': 'S', 'І': 'I', 'Ј': 'J',
+	// Greek
+	'α': 'a', 'ο': 'o', 'ν': 'v', 'ρ': 'p', 'ι': 'i', 'κ': 'k', 'υ': 'u',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O',
+	'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+	// Latin lookalikes
+	'ı': 'i', 'ɡ': 'g', 'ℓ': 'l',
+}
+
+// Returns the ASCII character that r is easily mistaken for, if there is one.
+func asciiConfusable(r rune) (rune, bool) {
+	// Fullwidth forms of ASCII, such as 'Ａ'
+	if r >= '！' && r <= '～' {
+		return r - '！' + '!', true
+	}
+
+	c, ok := asciiConfusables[r]
+
+	return c, ok
+}
+
+// Records a warning if ident contains characters that make it look like a different, ASCII, identifier.
+//
+// Identifiers written entirely in another script are fine unless every letter in them has an ASCII lookalike,
+// as then the whole identifier can be mistaken for an ASCII one.
+func (l *Lexer) warnConfusables(ident string, loc Location) {
+	var (
+		skeleton              strings.Builder
+		confusables           []string
+		hasASCII              bool
+		allNonASCIIConfusable = true
+	)
+
+	for _, r := range ident {
+		if c, ok := asciiConfusable(r); ok {
+			skeleton.WriteRune(c)
+
+			if desc := fmt.Sprintf("%#U looks like '%c'", r, c); !slices.Contains(confusables, desc) {
+				confusables = append(confusables, desc)
+			}
+
+			continue
+		}
+
+		skeleton.WriteRune(r)
+
+		if r <= unicode.MaxASCII {
+			hasASCII = hasASCII || unicode.IsLetter(r)
+		} else {
+			allNonASCIIConfusable = false
+		}
+	}
+
+	if len(confusables) == 0 || !(hasASCII || allNonASCIIConfusable) {
+		return
+	}
+
+	l.warnings = append(l.warnings, InitLexWarning(
+		loc,
+		ConfusableCharacterLexWarningReason,
+		fmt.Sprintf("'%s' could be mistaken for '%s' (%s)", ident, skeleton.String(), strings.Join(confusables, ", ")),
+	))
+}
@@ -1,43 +1,271 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"slices"
+	"strings"
 
 	"ljpprojects.org/sqopl/lexer"
 	"ljpprojects.org/sqopl/parser"
 )
 
-func main() {
-	log.SetOutput(io.Discard)
+// Exit codes, so that scripts can tell why a command failed.
+const (
+	exitSuccess = 0
+	// Some input has an error in it, such as an unknown character or a syntax error.
+	exitSourceError = 1
+	// The command line is invalid.
+	exitUsageError = 2
+	// An input could not be read, or an output could not be written.
+	exitIOError = 3
+	// The command exists, but cannot do its job yet.
+	exitUnsupported = 4
+)
 
-	file, err := os.Open("./test.sqopl")
+type usageError struct {
+	msg string
+}
 
-	if err != nil {
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// An invalid command line that the flag package has already reported, along with the usage.
+type flagError struct {
+	err error
+}
+
+func (e flagError) Error() string {
+	return e.err.Error()
+}
+
+type ioError struct {
+	err error
+}
+
+func (e ioError) Error() string {
+	return e.err.Error()
+}
+
+func (e ioError) Unwrap() error {
+	return e.err
+}
+
+// An error in the code of a source, such as a lex or parse error.
+type sourceError struct {
+	name string
+	err  error
+}
+
+func (e sourceError) Error() string {
+	return fmt.Sprintf("%s: %s", e.name, e.err)
+}
+
+func (e sourceError) Unwrap() error {
+	return e.err
+}
+
+type unsupportedError struct {
+	msg string
+}
+
+func (e unsupportedError) Error() string {
+	return e.msg
+}
+
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"parse":  {"print the syntax tree of each file", runParse},
+	"check":  {"report errors in each file without printing anything else", runCheck},
+	"run":    {"run a program", runRun},
+	"fmt":    {"normalise the whitespace of each file", runFmt},
+	"tokens": {"print the tokens of each file", runTokens},
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: sqopl [--verbose] COMMAND [FLAGS] FILE...")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "FILE may be a glob pattern, or - to read from standard input.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].summary)
+	}
+}
+
+// Returns the exit code for the most serious error in err.
+func exitCode(err error) int {
+	var (
+		usage       usageError
+		flags       flagError
+		ioErr       ioError
+		unsupported unsupportedError
+	)
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitSuccess
+	case errors.As(err, &usage), errors.As(err, &flags):
+		return exitUsageError
+	case errors.As(err, &ioErr):
+		return exitIOError
+	case errors.As(err, &unsupported):
+		return exitUnsupported
+	}
+
+	return exitSourceError
+}
+
+// Parses the flags of a command, including the --verbose flag that every command accepts, then reads its input files.
+func parseCommandLine(flags *flag.FlagSet, args []string) ([]*lexer.Source, error) {
+	verbose := flags.Bool("verbose", false, "print the compiler's internal tracing to stderr")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+
+		return nil, flagError{err}
+	}
+
+	if *verbose {
 		log.SetOutput(os.Stderr)
-		log.Fatal(err)
 	}
 
-	lexer := lexer.NewLexer(file)
-	parser := parser.NewParser(lexer)
+	return readInputs(flags.Args())
+}
+
+// Parses every statement in source, calling each with them in order.
+func parseSource(source *lexer.Source, each func(parser.Statement)) error {
+	p := parser.NewParser(lexer.NewLexer(source))
 
 	for {
-		mnd, err := parser.ParseStatement()
+		mnd, err := p.ParseStatement()
 
 		if err != nil {
-			log.SetOutput(os.Stderr)
-			log.Fatal(err)
+			return sourceError{source.Name(), err}
 		}
 
 		nd, err := mnd.Value()
 
 		if err != nil {
-			break
+			return nil
 		}
 
-		fmt.Println(nd)
-		fmt.Printf("%d-%d\n", nd.Location().Start, nd.Location().End)
+		each(nd)
 	}
 }
+
+func runParse(args []string) error {
+	sources, err := parseCommandLine(flag.NewFlagSet("parse", flag.ContinueOnError), args)
+
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, source := range sources {
+		if len(sources) > 1 {
+			fmt.Printf("==> %s <==\n", source.Name())
+		}
+
+		errs = append(errs, parseSource(source, func(nd parser.Statement) {
+			fmt.Println(nd)
+			fmt.Printf("%d-%d\n", nd.Location().Start, nd.Location().End)
+		}))
+	}
+
+	return errors.Join(errs...)
+}
+
+func runCheck(args []string) error {
+	sources, err := parseCommandLine(flag.NewFlagSet("check", flag.ContinueOnError), args)
+
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, source := range sources {
+		errs = append(errs, parseSource(source, func(parser.Statement) {}))
+	}
+
+	return errors.Join(errs...)
+}
+
+func runRun(args []string) error {
+	sources, err := parseCommandLine(flag.NewFlagSet("run", flag.ContinueOnError), args)
+
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, source := range sources {
+		errs = append(errs, parseSource(source, func(parser.Statement) {}))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	return unsupportedError{"running programs is not supported yet, use sqopl check to find errors in them"}
+}
+
+func main() {
+	log.SetOutput(io.Discard)
+
+	flag.Usage = printUsage
+	verbose := flag.Bool("verbose", false, "print the compiler's internal tracing to stderr")
+	flag.Parse()
+
+	if *verbose {
+		log.SetOutput(os.Stderr)
+	}
+
+	if flag.NArg() == 0 {
+		printUsage()
+		os.Exit(exitUsageError)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+
+	if !ok {
+		fmt.Fprintf(os.Stderr, "sqopl: unknown command %q\n\n", flag.Arg(0))
+		printUsage()
+		os.Exit(exitUsageError)
+	}
+
+	err := cmd.run(flag.Args()[1:])
+
+	var flags flagError
+
+	if err != nil && !errors.Is(err, flag.ErrHelp) && !errors.As(err, &flags) {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "sqopl %s: %s\n", flag.Arg(0), line)
+		}
+	}
+
+	os.Exit(exitCode(err))
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"math/big"
+
 	"ljpprojects.org/sqopl/lexer"
 	"ljpprojects.org/sqopl/utils"
 )
@@ -82,6 +84,8 @@ const (
 	SliceTypeASTNodeKind
 	TupleTypeASTNodeKind
 	ComputedVarDefinitionASTNodeKind
+	CharacterLiteralASTNodeKind
+	NullableTypeASTNodeKind
 )
 
 func (k ASTNodeKind) ToDisplayString() string {
@@ -234,6 +238,10 @@ func (k ASTNodeKind) ToDisplayString() string {
 		return "Kind(Tuple Type)"
 	case ComputedVarDefinitionASTNodeKind:
 		return "Kind(Computed Var Definition)"
+	case CharacterLiteralASTNodeKind:
+		return "Kind(Character Literal)"
+	case NullableTypeASTNodeKind:
+		return "Kind(Nullable Type)"
 	}
 
 	return "Unknown"
@@ -253,6 +261,7 @@ var (
 		ArrayTypeASTNodeKind,
 		SliceTypeASTNodeKind,
 		TupleTypeASTNodeKind,
+		NullableTypeASTNodeKind,
 	}
 
 	MetaASTNodeGroup ASTNodeGroup = ASTNodeGroup{
@@ -312,6 +321,7 @@ var (
 		ArrayLiteralASTNodeKind,
 		IntegerLiteralASTNodeKind,
 		DecimalLiteralASTNodeKind,
+		CharacterLiteralASTNodeKind,
 		IdentifierLiteralASTNodeKind,
 	}
 
@@ -415,7 +425,10 @@ type Type interface {
 }
 
 type (
+	// A generic parameter of a definition, such as the U: Comparable & Hashable in fn Sort<U: Comparable & Hashable>.
 	TypeGenericASTNode struct {
+		Loc        lexer.Location
+		Name       string
 		ConformsTo []NamedTypeASTNode
 	}
 
@@ -452,9 +465,12 @@ type (
 	}
 
 	NamedTypeASTNode struct {
-		Loc      lexer.Location
-		Name     string
-		Generics map[string]TypeGenericASTNode
+		Loc lexer.Location
+		// The modules that the type is in, such as [core] in core:CInt
+		Module []string
+		Name   string
+		// The types given for the generic parameters of the type, such as [T] in List<T>
+		GenericArguments []Type
 	}
 
 	UntaggedUnionTypeASTNode struct {
@@ -484,6 +500,12 @@ type (
 		Loc        lexer.Location
 		ValueTypes []Type
 	}
+
+	// A type whose values may also be null, such as the reference in mut&? T.
+	NullableTypeASTNode struct {
+		Loc   lexer.Location
+		Inner Type
+	}
 )
 
 func (ptr RawPointer) typeNode()               {}
@@ -496,6 +518,7 @@ func (typ TableTypeASTNode) typeNode()         {}
 func (typ ArrayTypeASTNode) typeNode()         {}
 func (typ SliceTypeASTNode) typeNode()         {}
 func (typ TupleTypeASTNode) typeNode()         {}
+func (typ NullableTypeASTNode) typeNode()      {}
 
 func (ptr RawPointer) Mutable() bool         { return true }
 func (ref MutableReference) Mutable() bool   { return true }
@@ -522,6 +545,7 @@ func (typ TableTypeASTNode) Location() lexer.Location         { return typ.Loc }
 func (typ ArrayTypeASTNode) Location() lexer.Location         { return typ.Loc }
 func (typ SliceTypeASTNode) Location() lexer.Location         { return typ.Loc }
 func (typ TupleTypeASTNode) Location() lexer.Location         { return typ.Loc }
+func (typ NullableTypeASTNode) Location() lexer.Location      { return typ.Loc }
 
 func (ptr RawPointer) Kind() ASTNodeKind               { return RawPointerTypeASTNodeKind }
 func (ref MutableReference) Kind() ASTNodeKind         { return MutableReferenceTypeASTNodeKind }
@@ -533,6 +557,7 @@ func (typ TableTypeASTNode) Kind() ASTNodeKind         { return TableTypeASTNode
 func (typ ArrayTypeASTNode) Kind() ASTNodeKind         { return ArrayTypeASTNodeKind }
 func (typ SliceTypeASTNode) Kind() ASTNodeKind         { return SliceTypeASTNodeKind }
 func (typ TupleTypeASTNode) Kind() ASTNodeKind         { return TupleTypeASTNodeKind }
+func (typ NullableTypeASTNode) Kind() ASTNodeKind      { return NullableTypeASTNodeKind }
 
 func (ptr RawPointer) Group() ASTNodeGroup               { return TypeASTNodeGroup }
 func (ref MutableReference) Group() ASTNodeGroup         { return TypeASTNodeGroup }
@@ -544,11 +569,27 @@ func (typ TableTypeASTNode) Group() ASTNodeGroup         { return TypeASTNodeGro
 func (typ ArrayTypeASTNode) Group() ASTNodeGroup         { return TypeASTNodeGroup }
 func (typ SliceTypeASTNode) Group() ASTNodeGroup         { return TypeASTNodeGroup }
 func (typ TupleTypeASTNode) Group() ASTNodeGroup         { return TypeASTNodeGroup }
+func (typ NullableTypeASTNode) Group() ASTNodeGroup      { return TypeASTNodeGroup }
 
 type (
+	// A part of an import, such as std:data:{List, Map as Dictionary}.
+	// The path is followed by at most one of an alias, a wildcard, or a braced group of more imports.
+	ImportTree struct {
+		Loc lexer.Location
+		// Empty for a group or wildcard that is not inside a module, as in {io, exitcode}
+		Path []string
+		// Empty unless the module is renamed with "as"
+		Alias      string
+		IsWildcard bool
+		// The imports in braces after the path, which are relative to it
+		Children []ImportTree
+	}
+
 	ImportStatementASTNode struct {
 		Loc  lexer.Location
-		Path []string
+		Tree ImportTree
+		// Set for "import?", which macros use to import modules that the code around them may already import
+		IsOptional bool
 	}
 
 	ConstDefinitionASTNode struct {
@@ -559,16 +600,18 @@ type (
 	}
 
 	VarDefinitionASTNode struct {
-		Loc   lexer.Location
-		Name  string
-		Value Expression
+		Loc  lexer.Location
+		Name string
+		// None when the value is assigned later, as in let x Integer32;
+		Value utils.Optional[Expression]
 		Type  utils.Optional[Type]
 	}
 
 	LetDefinitionASTNode struct {
-		Loc   lexer.Location
-		Name  string
-		Value Expression
+		Loc  lexer.Location
+		Name string
+		// None when the value is assigned later, as in let x Integer32;
+		Value utils.Optional[Expression]
 		Type  utils.Optional[Type]
 	}
 
@@ -579,58 +622,64 @@ type (
 
 	BinaryExpressionASTNode struct {
 		Loc      lexer.Location
-		Operator string
+		Operator lexer.OperatorKind
 		Left     Expression
 		Right    Expression
 	}
 
 	PostfixUnaryExpressionASTNode struct {
 		Loc      lexer.Location
-		Operator string
+		Operator lexer.OperatorKind
 		Left     Expression
 	}
 
 	PrefixUnaryExpressionASTNode struct {
 		Loc      lexer.Location
-		Operator string
+		Operator lexer.OperatorKind
 		Right    Expression
 	}
 
-	StructureDefFields []struct {
+	// A field of a struct or class, which is declared with var if it is mutable and let otherwise.
+	FieldDefinition struct {
 		Loc       lexer.Location
 		Name      string
 		IsMutable bool
 		Type      Type
 	}
 
+	StructureDefFields []FieldDefinition
+
 	StructureDefinitionASTNode struct {
 		Loc    lexer.Location
 		Name   string
 		Fields StructureDefFields
 	}
 
-	ClassDefFields []struct {
-		Loc       lexer.Location
-		Name      string
-		IsMutable bool
-		Type      Type
-	}
+	ClassDefFields []FieldDefinition
 
-	ClassDefMethods []struct {
-		Loc           lexer.Location
-		Name          string
-		ReturnType    Type
-		ArgumentTypes []Type
-		SelfType      utils.Optional[Type]
+	ClassDefMethod struct {
+		Loc        lexer.Location
+		Name       string
+		ReturnType Type
+		Parameters []FunctionParameter
+		Generics   []TypeGenericASTNode
+		// None for a static method. The inner type of the reference is nil, since it is always the class.
+		SelfType utils.Optional[RefType]
+		Body     BlockASTNode
 	}
 
-	ClassDefConstructors []struct {
-		Loc           lexer.Location
-		Name          string
+	ClassDefMethods []ClassDefMethod
+
+	ClassDefConstructor struct {
+		Loc lexer.Location
+		// Set for a failable constructor, new?, which produces null instead of an instance if it fails
 		MayReturnNull bool
-		ArgumentTypes []Type
+		Parameters    []FunctionParameter
+		Body          BlockASTNode
 	}
 
+	ClassDefConstructors []ClassDefConstructor
+
 	ClassDefinitionASTNode struct {
 		Loc          lexer.Location
 		Name         string
@@ -639,38 +688,51 @@ type (
 		Constructors ClassDefConstructors
 	}
 
+	// A parameter of a function, which callers label with its name, as in Data(number: 0).
+	FunctionParameter struct {
+		Loc  lexer.Location
+		Name string
+		Type Type
+	}
+
 	FunctionDefinitionASTNode struct {
-		Loc        lexer.Location
-		Name       string
+		Loc  lexer.Location
+		Name string
+		// nil if the function does not return a value
 		ReturnType Type
-		Parameters map[string]Type
-		Generics   map[string]TypeGenericASTNode
-		Body       BlockASTNode
+		Parameters []FunctionParameter
+		Generics   []TypeGenericASTNode
+		// None for a prototype, which declares a function defined elsewhere
+		Body utils.Optional[BlockASTNode]
 	}
 
 	MethodDefinitionASTNode struct {
 		Loc         lexer.Location
 		Name        string
 		ReturnType  Type
-		Parameters  map[string]Type
+		Parameters  []FunctionParameter
 		ContextType Type
-		Generics    map[string]TypeGenericASTNode
+		Generics    []TypeGenericASTNode
 		Body        BlockASTNode
 	}
 
 	OperatorOverloadASTNode struct {
-		Loc           lexer.Location
-		Operator      string
-		ReturnType    Type
+		Loc        lexer.Location
+		Operator   lexer.OperatorKind
+		ReturnType Type
+		// The left-hand operand, or the only operand of a unary operator, followed by the right-hand operand
+		Parameters    []FunctionParameter
 		ContextType   Type
 		RightHandType Type
 		Body          BlockASTNode
 	}
 
 	AssignmentStatementASTNode struct {
-		Loc   lexer.Location
-		Left  Expression
-		Right Expression
+		Loc lexer.Location
+		// EqualsOperatorKind for a plain assignment, or the compound assignment operator, such as PlusEqualsOperatorKind
+		Operator lexer.OperatorKind
+		Left     Expression
+		Right    Expression
 	}
 
 	StructureInitilisationExpressionASTNode struct {
@@ -692,15 +754,24 @@ type (
 	}
 
 	ExplicitReturnASTNode struct {
+		Loc lexer.Location
+		// None for a return without a value
+		Value utils.Optional[Expression]
+	}
+
+	// A labelled argument of a call, such as the str: str in Integer32(str: str).
+	CallArgument struct {
 		Loc   lexer.Location
+		Label string
 		Value Expression
 	}
 
 	FunctionCallExpressionASTNode struct {
-		Loc        lexer.Location
-		Name       string
-		Parameters map[string]Expression
-		Generics   map[string]TypeGenericASTNode
+		Loc lexer.Location
+		// The function being called, which for a method call is a MemberExpressionASTNode such as val.ConvertToInteger32
+		Callee    Expression
+		Arguments []CallArgument
+		Generics  map[string]TypeGenericASTNode
 	}
 
 	MethodCallExpressionASTNode struct {
@@ -711,6 +782,8 @@ type (
 		Generics   map[string]TypeGenericASTNode
 	}
 
+	// The first segment is the value whose members are accessed, and each later segment is an IdentifierLiteralASTNode naming a member,
+	// so self.data.number has three segments.
 	MemberExpressionASTNode struct {
 		Loc      lexer.Location
 		Segments []Expression
@@ -723,7 +796,7 @@ type (
 
 	LambdaExpressionASTNode struct {
 		Loc        lexer.Location
-		Parameters map[string]Type
+		Parameters []FunctionParameter
 		ReturnType Type
 		Body       BlockASTNode
 	}
@@ -795,9 +868,9 @@ type (
 	InterfaceDefMethod struct {
 		Loc         lexer.Location
 		ReturnType  Type
-		Parameters  map[string]Type
+		Parameters  []FunctionParameter
 		ContextType Type
-		Generics    map[string]TypeGenericASTNode
+		Generics    []TypeGenericASTNode
 	}
 
 	InterfaceDefinitionASTNode struct {
@@ -805,7 +878,7 @@ type (
 		Extends  []NamedTypeASTNode
 		Fields   map[string]InterfaceDefField
 		Methods  map[string]InterfaceDefMethod
-		Generics map[string]TypeGenericASTNode
+		Generics []TypeGenericASTNode
 	}
 
 	StringLiteralASTNode struct {
@@ -819,8 +892,10 @@ type (
 	}
 
 	IntegerLiteralASTNode struct {
-		Loc   lexer.Location
-		Value int64
+		Loc lexer.Location
+		// Integer literals have arbitrary precision, and are only checked against their type's range once it is known
+		Value  *big.Int
+		Suffix lexer.IntegerSuffixKind
 	}
 
 	DecimalLiteralASTNode struct {
@@ -828,6 +903,11 @@ type (
 		Value float64
 	}
 
+	CharacterLiteralASTNode struct {
+		Loc   lexer.Location
+		Value rune
+	}
+
 	CStyleEnumDefinitionASTNode struct {
 		Loc         lexer.Location
 		Name        string
@@ -856,7 +936,7 @@ type (
 		Loc        lexer.Location
 		Name       string
 		ReturnType Type
-		Parameters map[string]Type
+		Parameters []FunctionParameter
 	}
 
 	CStyleForLoopStatementASTNode struct {
@@ -892,6 +972,7 @@ type (
 		FallbackValue Expression
 	}
 
+	// Laid out like MemberExpressionASTNode, for members accessed with "?.", which give null if the value before them is null.
 	OptionalChainingASTNode struct {
 		Loc   lexer.Location
 		Chain []Expression
@@ -1003,18 +1084,20 @@ type (
 
 	BubbleValueToReturnASTNode struct {
 		Loc   lexer.Location
-		value Expression
+		Value Expression
 	}
 
 	GetterMethodDef struct {
-		Loc      lexer.Location
-		Body     BlockASTNode
+		Loc  lexer.Location
+		Body BlockASTNode
+		// nil for a computed var outside of a type, which has no self
 		SelfType RefType
 	}
 
 	SetterMethodDef struct {
-		Loc             lexer.Location
-		Body            BlockASTNode
+		Loc  lexer.Location
+		Body BlockASTNode
+		// nil for a computed var outside of a type, which has no self
 		SelfType        RefType
 		NewValueArgName string
 	}
@@ -1062,6 +1145,7 @@ StringLiteralASTNode
 ArrayLiteralASTNode
 IntegerLiteralASTNode
 DecimalLiteralASTNode
+CharacterLiteralASTNode
 CStyleEnumDefinitionASTNode
 SumTypeEnumDefinitionASTNode
 NamespaceDefinitionASTNode
@@ -1129,9 +1213,11 @@ func (node StringLiteralASTNode) Location() lexer.Location
 func (node ArrayLiteralASTNode) Location() lexer.Location                        { return node.Loc }
 func (node IntegerLiteralASTNode) Location() lexer.Location                      { return node.Loc }
 func (node DecimalLiteralASTNode) Location() lexer.Location                      { return node.Loc }
+func (node CharacterLiteralASTNode) Location() lexer.Location                    { return node.Loc }
 func (node CStyleEnumDefinitionASTNode) Location() lexer.Location                { return node.Loc }
 func (node SumTypeEnumDefinitionASTNode) Location() lexer.Location               { return node.Loc }
 func (node NamespaceDefinitionASTNode) Location() lexer.Location                 { return node.Loc }
+func (node ComputedVarDefinitionASTNode) Location() lexer.Location               { return node.Loc }
 func (node ExternalFnDeclarationASTNode) Location() lexer.Location               { return node.Loc }
 func (node CStyleForLoopStatementASTNode) Location() lexer.Location              { return node.Loc }
 func (node ForInLoopStatementASTNode) Location() lexer.Location                  { return node.Loc }
@@ -1197,9 +1283,13 @@ func (node StringLiteralASTNode) Kind() ASTNodeKind         { return StringLiter
 func (node ArrayLiteralASTNode) Kind() ASTNodeKind          { return ArrayLiteralASTNodeKind }
 func (node IntegerLiteralASTNode) Kind() ASTNodeKind        { return IntegerLiteralASTNodeKind }
 func (node DecimalLiteralASTNode) Kind() ASTNodeKind        { return DecimalLiteralASTNodeKind }
+func (node CharacterLiteralASTNode) Kind() ASTNodeKind      { return CharacterLiteralASTNodeKind }
 func (node CStyleEnumDefinitionASTNode) Kind() ASTNodeKind  { return CStyleEnumDefinitionASTNodeKind }
 func (node SumTypeEnumDefinitionASTNode) Kind() ASTNodeKind { return SumTypeEnumDefinitionASTNodeKind }
 func (node NamespaceDefinitionASTNode) Kind() ASTNodeKind   { return NamespaceDefinitionASTNodeKind }
+func (node ComputedVarDefinitionASTNode) Kind() ASTNodeKind {
+	return ComputedVarDefinitionASTNodeKind
+}
 func (node ExternalFnDeclarationASTNode) Kind() ASTNodeKind { return ExternalFnDeclarationASTNodeKind }
 func (node CStyleForLoopStatementASTNode) Kind() ASTNodeKind {
 	return CStyleForLoopStatementASTNodeKind
@@ -1266,6 +1356,9 @@ func (node ForInLoopStatementASTNode) statementNode()     {}
 func (node WhileLoopStatementASTNode) statementNode()     {}
 func (node ForeverLoopStatementASTNode) statementNode()   {}
 
+// Assignments are parsed as the loosest expressions, though they are only useful as statements.
+func (node AssignmentStatementASTNode) expressionNode() {}
+
 func (node ConstDefinitionASTNode) Group() ASTNodeGroup       { return DefinitionASTNodeGroup }
 func (node VarDefinitionASTNode) Group() ASTNodeGroup         { return DefinitionASTNodeGroup }
 func (node LetDefinitionASTNode) Group() ASTNodeGroup         { return DefinitionASTNodeGroup }
@@ -1278,6 +1371,7 @@ func (node InterfaceDefinitionASTNode) Group() ASTNodeGroup   { return Definitio
 func (node CStyleEnumDefinitionASTNode) Group() ASTNodeGroup  { return DefinitionASTNodeGroup }
 func (node SumTypeEnumDefinitionASTNode) Group() ASTNodeGroup { return DefinitionASTNodeGroup }
 func (node NamespaceDefinitionASTNode) Group() ASTNodeGroup   { return DefinitionASTNodeGroup }
+func (node ComputedVarDefinitionASTNode) Group() ASTNodeGroup { return DefinitionASTNodeGroup }
 
 func (node ConstDefinitionASTNode) statementNode()         {}
 func (node VarDefinitionASTNode) statementNode()           {}
@@ -1291,6 +1385,7 @@ func (node InterfaceDefinitionASTNode) statementNode()     {}
 func (node CStyleEnumDefinitionASTNode) statementNode()    {}
 func (node SumTypeEnumDefinitionASTNode) statementNode()   {}
 func (node NamespaceDefinitionASTNode) statementNode()     {}
+func (node ComputedVarDefinitionASTNode) statementNode()   {}
 func (node ConstDefinitionASTNode) declarationNode()       {}
 func (node VarDefinitionASTNode) declarationNode()         {}
 func (node LetDefinitionASTNode) declarationNode()         {}
@@ -1303,6 +1398,7 @@ func (node InterfaceDefinitionASTNode) declarationNode()   {}
 func (node CStyleEnumDefinitionASTNode) declarationNode()  {}
 func (node SumTypeEnumDefinitionASTNode) declarationNode() {}
 func (node NamespaceDefinitionASTNode) declarationNode()   {}
+func (node ComputedVarDefinitionASTNode) declarationNode() {}
 func (node ConstDefinitionASTNode) definitionNode()        {}
 func (node VarDefinitionASTNode) definitionNode()          {}
 func (node LetDefinitionASTNode) definitionNode()          {}
@@ -1315,27 +1411,32 @@ func (node InterfaceDefinitionASTNode) definitionNode()    {}
 func (node CStyleEnumDefinitionASTNode) definitionNode()   {}
 func (node SumTypeEnumDefinitionASTNode) definitionNode()  {}
 func (node NamespaceDefinitionASTNode) definitionNode()    {}
+func (node ComputedVarDefinitionASTNode) definitionNode()  {}
 
 func (node StringLiteralASTNode) Group() ASTNodeGroup     { return LiteralASTNodeGroup }
 func (node ArrayLiteralASTNode) Group() ASTNodeGroup      { return LiteralASTNodeGroup }
 func (node IntegerLiteralASTNode) Group() ASTNodeGroup    { return LiteralASTNodeGroup }
 func (node DecimalLiteralASTNode) Group() ASTNodeGroup    { return LiteralASTNodeGroup }
+func (node CharacterLiteralASTNode) Group() ASTNodeGroup  { return LiteralASTNodeGroup }
 func (node IdentifierLiteralASTNode) Group() ASTNodeGroup { return LiteralASTNodeGroup }
 
 func (node StringLiteralASTNode) statementNode()      {}
 func (node ArrayLiteralASTNode) statementNode()       {}
 func (node IntegerLiteralASTNode) statementNode()     {}
 func (node DecimalLiteralASTNode) statementNode()     {}
+func (node CharacterLiteralASTNode) statementNode()   {}
 func (node IdentifierLiteralASTNode) statementNode()  {}
 func (node StringLiteralASTNode) expressionNode()     {}
 func (node ArrayLiteralASTNode) expressionNode()      {}
 func (node IntegerLiteralASTNode) expressionNode()    {}
 func (node DecimalLiteralASTNode) expressionNode()    {}
+func (node CharacterLiteralASTNode) expressionNode()  {}
 func (node IdentifierLiteralASTNode) expressionNode() {}
 func (node StringLiteralASTNode) literalNode()        {}
 func (node ArrayLiteralASTNode) literalNode()         {}
 func (node IntegerLiteralASTNode) literalNode()       {}
 func (node DecimalLiteralASTNode) literalNode()       {}
+func (node CharacterLiteralASTNode) literalNode()     {}
 func (node IdentifierLiteralASTNode) literalNode()    {}
 
 func (node ExternalFnDeclarationASTNode) Group() ASTNodeGroup { return DeclarationASTNodeGroup }
@@ -1383,6 +1484,7 @@ func (node TernaryExpressionASTNode) Group() ASTNodeGroup           { return Exp
 func (node OptionalChainingASTNode) Group() ASTNodeGroup            { return ExpressionASTNodeGroup }
 func (node TypeCastableQueryExpressionASTNode) Group() ASTNodeGroup { return ExpressionASTNodeGroup }
 func (node TypeCastExpressionASTNode) Group() ASTNodeGroup          { return ExpressionASTNodeGroup }
+func (node RuntimeTypeCastExpressionASTNode) Group() ASTNodeGroup   { return ExpressionASTNodeGroup }
 
 func (node IfLetExpressionASTNode) statementNode()                      {}
 func (node IfVarExpressionASTNode) statementNode()                      {}
@@ -1405,6 +1507,7 @@ func (node TernaryExpressionASTNode) statementNode()                    {}
 func (node OptionalChainingASTNode) statementNode()                     {}
 func (node TypeCastableQueryExpressionASTNode) statementNode()          {}
 func (node TypeCastExpressionASTNode) statementNode()                   {}
+func (node RuntimeTypeCastExpressionASTNode) statementNode()            {}
 func (node IfLetExpressionASTNode) expressionNode()                     {}
 func (node IfVarExpressionASTNode) expressionNode()                     {}
 func (node NullCoalesceExpressionASTNode) expressionNode()              {}
@@ -1426,3 +1529,4 @@ func (node TernaryExpressionASTNode) expressionNode()                   {}
 func (node OptionalChainingASTNode) expressionNode()                    {}
 func (node TypeCastableQueryExpressionASTNode) expressionNode()         {}
 func (node TypeCastExpressionASTNode) expressionNode()                  {}
+func (node RuntimeTypeCastExpressionASTNode) expressionNode()           {}
@@ -0,0 +1,326 @@
+package parser
+
+import (
+	"ljpprojects.org/sqopl/lexer"
+	"ljpprojects.org/sqopl/utils"
+)
+
+// Parses a struct definition, which is "struct" Name "{" {Field} "}", such as:
+//
+//	struct Data {
+//	    let Number Integer32
+//	}
+func (p *Parser) ParseStructureDefinition() (Definition, error) {
+	mtk, err := p.ExpectKeyword(lexer.StructKeywordKind)
+	tk, err := requireToken(mtk, err, StructureDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mtk, err = p.ExpectIdentifier()
+	nameTk, err := requireToken(mtk, err, StructureDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mtk, err = p.ExpectCharacter('{', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, StructureDefinitionASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	fields := StructureDefFields{}
+
+	for {
+		closed, err := p.acceptMatching(isCharacter('}', lexer.GroupingTokenKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if closed {
+			break
+		}
+
+		field, err := p.parseFieldDefinition(StructureDefinitionASTNodeKind)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, field)
+	}
+
+	return StructureDefinitionASTNode{
+		Loc:    lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		Name:   nameTk.IdentifierName(),
+		Fields: fields,
+	}, nil
+}
+
+// Parses a class definition, which is "class" Name "{" {Field | Constructor | Method} "}", such as:
+//
+//	class Data {
+//	    let number Integer32;
+//
+//	    new(number Integer32) { ... }
+//	    new?(str String) { ... }
+//
+//	    fn Static -> Data { ... }
+//	    fn Number(const& self) -> Integer32 { ... }
+//	}
+//
+// Methods without a self parameter are static.
+func (p *Parser) ParseClassDefinition() (Definition, error) {
+	mtk, err := p.ExpectKeyword(lexer.ClassKeywordKind)
+	tk, err := requireToken(mtk, err, ClassDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mtk, err = p.ExpectIdentifier()
+	nameTk, err := requireToken(mtk, err, ClassDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mtk, err = p.ExpectCharacter('{', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, ClassDefinitionASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	class := ClassDefinitionASTNode{
+		Name:         nameTk.IdentifierName(),
+		Fields:       ClassDefFields{},
+		Methods:      ClassDefMethods{},
+		Constructors: ClassDefConstructors{},
+	}
+
+	for {
+		member, err := p.requirePeekToken(ClassDefinitionASTNodeKind)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if isCharacter('}', lexer.GroupingTokenKind)(member) {
+			if _, err := p.NextToken(); err != nil {
+				return nil, err
+			}
+
+			break
+		}
+
+		switch member.Keyword() {
+		case lexer.LetKeywordKind, lexer.VarKeywordKind:
+			field, err := p.parseFieldDefinition(ClassDefinitionASTNodeKind)
+
+			if err != nil {
+				return nil, err
+			}
+
+			class.Fields = append(class.Fields, field)
+		case lexer.NewKeywordKind:
+			constructor, err := p.parseConstructor()
+
+			if err != nil {
+				return nil, err
+			}
+
+			class.Constructors = append(class.Constructors, constructor)
+		case lexer.FnKeywordKind:
+			method, err := p.parseClassMethod()
+
+			if err != nil {
+				return nil, err
+			}
+
+			class.Methods = append(class.Methods, method)
+		default:
+			return nil, ParseErrorUnexpectedToken{
+				Got:          member,
+				WhileParsing: ClassDefinitionASTNodeKind,
+			}
+		}
+	}
+
+	class.Loc = lexer.InitLocation(tk.Startpos(), p.currentPos())
+
+	return class, nil
+}
+
+// Parses a field, which is ("let" | "var") Name Type [";"].
+func (p *Parser) parseFieldDefinition(whileParsing ASTNodeKind) (FieldDefinition, error) {
+	tk, err := p.requireNextToken(whileParsing)
+
+	if err != nil {
+		return FieldDefinition{}, err
+	}
+
+	if tk.Keyword() != lexer.LetKeywordKind && tk.Keyword() != lexer.VarKeywordKind {
+		return FieldDefinition{}, ParseErrorUnexpectedToken{
+			Got:          tk,
+			WhileParsing: whileParsing,
+		}
+	}
+
+	mtk, err := p.ExpectIdentifier()
+	nameTk, err := requireToken(mtk, err, whileParsing)
+
+	if err != nil {
+		return FieldDefinition{}, err
+	}
+
+	typ, err := p.ParseType()
+
+	if err != nil {
+		return FieldDefinition{}, err
+	}
+
+	if _, err := p.acceptMatching(isCharacter(';', lexer.SeparatorTokenKind)); err != nil {
+		return FieldDefinition{}, err
+	}
+
+	return FieldDefinition{
+		Loc:       lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		Name:      nameTk.IdentifierName(),
+		IsMutable: tk.Keyword() == lexer.VarKeywordKind,
+		Type:      typ,
+	}, nil
+}
+
+// Parses a constructor, which is "new" ["?"] Parameters Block.
+func (p *Parser) parseConstructor() (ClassDefConstructor, error) {
+	mtk, err := p.ExpectKeyword(lexer.NewKeywordKind)
+	tk, err := requireToken(mtk, err, ClassDefinitionASTNodeKind)
+
+	if err != nil {
+		return ClassDefConstructor{}, err
+	}
+
+	failable, err := p.acceptMatching(isOperator(lexer.QuestionOperatorKind))
+
+	if err != nil {
+		return ClassDefConstructor{}, err
+	}
+
+	parameters, err := p.parseFunctionParameters(ClassDefinitionASTNodeKind)
+
+	if err != nil {
+		return ClassDefConstructor{}, err
+	}
+
+	body, err := p.ParseBlock()
+
+	if err != nil {
+		return ClassDefConstructor{}, err
+	}
+
+	return ClassDefConstructor{
+		Loc:           lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		MayReturnNull: failable,
+		Parameters:    parameters,
+		Body:          body,
+	}, nil
+}
+
+// Parses a method, which is "fn" Name [Generics] ["(" [SelfParameter ","] {Parameter ","} ")"] ["->" Type] Block.
+// The trailing ',' of the parameters may be left out.
+func (p *Parser) parseClassMethod() (ClassDefMethod, error) {
+	mtk, err := p.ExpectKeyword(lexer.FnKeywordKind)
+	tk, err := requireToken(mtk, err, ClassDefinitionASTNodeKind)
+
+	if err != nil {
+		return ClassDefMethod{}, err
+	}
+
+	mtk, err = p.ExpectIdentifier()
+	nameTk, err := requireToken(mtk, err, ClassDefinitionASTNodeKind)
+
+	if err != nil {
+		return ClassDefMethod{}, err
+	}
+
+	generics, err := p.parseGenericParameters()
+
+	if err != nil {
+		return ClassDefMethod{}, err
+	}
+
+	selfType := utils.NoneOptional[RefType]()
+	parameters := []FunctionParameter{}
+
+	hasParameters, err := p.acceptMatching(isCharacter('(', lexer.GroupingTokenKind))
+
+	if err != nil {
+		return ClassDefMethod{}, err
+	}
+
+	if hasParameters {
+		hasSelf, err := p.peekMatches(startsSelfParameter)
+
+		if err != nil {
+			return ClassDefMethod{}, err
+		}
+
+		if hasSelf {
+			ref, err := p.parseSelfParameter()
+
+			if err != nil {
+				return ClassDefMethod{}, err
+			}
+
+			selfType = utils.SomeOptional(ref)
+
+			// The other parameters must be separated from self by a ','
+			separated, err := p.acceptMatching(isCharacter(',', lexer.SeparatorTokenKind))
+
+			if err != nil {
+				return ClassDefMethod{}, err
+			}
+
+			if !separated {
+				mtk, err := p.ExpectCharacter(')', lexer.GroupingTokenKind)
+
+				if _, err := requireToken(mtk, err, ClassDefinitionASTNodeKind); err != nil {
+					return ClassDefMethod{}, err
+				}
+
+				hasParameters = false
+			}
+		}
+
+		if hasParameters {
+			if parameters, err = p.parseParameterList(ClassDefinitionASTNodeKind, parameters); err != nil {
+				return ClassDefMethod{}, err
+			}
+		}
+	}
+
+	returnType, err := p.parseReturnType()
+
+	if err != nil {
+		return ClassDefMethod{}, err
+	}
+
+	body, err := p.ParseBlock()
+
+	if err != nil {
+		return ClassDefMethod{}, err
+	}
+
+	return ClassDefMethod{
+		Loc:        lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		Name:       nameTk.IdentifierName(),
+		ReturnType: returnType,
+		Parameters: parameters,
+		Generics:   generics,
+		SelfType:   selfType,
+		Body:       body,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"testing"
+)
+
+// The class from syntax.sqopl, with a body and return type filled in for the placeholder Static method.
+const syntaxClass = `class Data {
+    let number Integer32;
+
+    new(number Integer32) {
+        self.number = number;
+    }
+
+    new?(str String) {
+        self.number = Integer32(str: str)?;
+    }
+
+    fn Static -> Data {
+        Data(number: 0)
+    }
+
+    fn Number(const& self) -> Integer32 {
+        self.number;
+    }
+}`
+
+func TestParseClassDefinition(t *testing.T) {
+	def, err := newTestParser(syntaxClass).ParseClassDefinition()
+
+	if err != nil {
+		t.Fatalf("parsing the class: %v", err)
+	}
+
+	class, ok := def.(ClassDefinitionASTNode)
+
+	if !ok {
+		t.Fatalf("got a %T, want a ClassDefinitionASTNode", def)
+	}
+
+	if class.Name != "Data" {
+		t.Errorf("got name %q, want Data", class.Name)
+	}
+
+	if len(class.Fields) != 1 || class.Fields[0].Name != "number" || class.Fields[0].IsMutable {
+		t.Errorf("got fields %v, want the immutable field number", class.Fields)
+	}
+
+	if len(class.Constructors) != 2 {
+		t.Fatalf("got %d constructors, want 2", len(class.Constructors))
+	}
+
+	if class.Constructors[0].MayReturnNull || !class.Constructors[1].MayReturnNull {
+		t.Errorf("only the second constructor should be able to return null")
+	}
+
+	bodies := []string{
+		"(self.number = number)",
+		"(self.number = (Integer32(str: str)?))",
+	}
+
+	for i, want := range bodies {
+		code := class.Constructors[i].Body.Code
+
+		if len(code) != 1 {
+			t.Errorf("constructor %d has %d statements, want 1", i, len(code))
+			continue
+		}
+
+		expr, ok := code[0].(Expression)
+
+		if !ok {
+			t.Errorf("constructor %d has a %T, want an expression", i, code[0])
+			continue
+		}
+
+		if got := describeExpression(expr); got != want {
+			t.Errorf("constructor %d assigns %s, want %s", i, got, want)
+		}
+	}
+
+	if len(class.Methods) != 2 {
+		t.Fatalf("got %d methods, want 2", len(class.Methods))
+	}
+
+	if _, err := class.Methods[0].SelfType.Value(); err == nil {
+		t.Errorf("Static has a self parameter, want none")
+	}
+
+	selfType, err := class.Methods[1].SelfType.Value()
+
+	if err != nil {
+		t.Fatalf("Number has no self parameter")
+	}
+
+	if _, ok := selfType.(ImmutableReference); !ok {
+		t.Errorf("Number's self parameter is a %T, want an ImmutableReference", selfType)
+	}
+
+	if pos := class.Location().End; pos.Line() != 19 {
+		t.Errorf("the class ends on line %d, want 19", pos.Line())
+	}
+}
+
+func TestParseStructureDefinition(t *testing.T) {
+	def, err := newTestParser("struct Data {\n    let Number Integer32\n    var Count Integer64;\n}").ParseStructureDefinition()
+
+	if err != nil {
+		t.Fatalf("parsing the struct: %v", err)
+	}
+
+	structure := def.(StructureDefinitionASTNode)
+
+	if len(structure.Fields) != 2 || structure.Fields[0].IsMutable || !structure.Fields[1].IsMutable {
+		t.Errorf("got fields %v, want Number then the mutable Count", structure.Fields)
+	}
+}
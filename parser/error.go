@@ -4,23 +4,24 @@ import (
 	"fmt"
 
 	"ljpprojects.org/sqopl/lexer"
+	"ljpprojects.org/sqopl/utils"
 )
 
 type ParseErrorExpectedCharacter struct {
-	Expected      rune
-	ExpectedGroup *lexer.TokenGroup
+	Expected     rune
+	ExpectedKind lexer.TokenKind
 
-	Got      rune
-	GotGroup *lexer.TokenGroup
+	Got     rune
+	GotKind lexer.TokenKind
 }
 
 func (e ParseErrorExpectedCharacter) Error() string {
 	return fmt.Sprintf(
-		"Expected character '%#U' of group %s, but got character '%#U' of group %s",
+		"Expected character '%#U' of kind %s, but got character '%#U' of kind %s",
 		e.Expected,
-		e.ExpectedGroup.ToDisplayString(),
+		e.ExpectedKind.ToDisplayString(),
 		e.Got,
-		e.GotGroup.ToDisplayString(),
+		e.GotKind.ToDisplayString(),
 	)
 }
 
@@ -37,6 +38,82 @@ func (e ParseErrorExpectedToken) Error() string {
 	)
 }
 
+type ParseErrorExpectedOperatorName struct {
+	Got lexer.Token
+}
+
+func (e ParseErrorExpectedOperatorName) Error() string {
+	return fmt.Sprintf(
+		"Expected an operator in backticks, like `+`, but got token %s",
+		e.Got.ToDisplayString(),
+	)
+}
+
+type ParseErrorUnexpectedToken struct {
+	Got          lexer.Token
+	WhileParsing ASTNodeKind
+}
+
+func (e ParseErrorUnexpectedToken) Error() string {
+	return fmt.Sprintf(
+		"Unexpected token %s while parsing node %s",
+		e.Got.ToDisplayString(),
+		e.WhileParsing.ToDisplayString(),
+	)
+}
+
+// An operator that is not followed by what it needs, such as its right-hand operand or the "else" of a ternary.
+type ParseErrorIncompleteOperator struct {
+	Operator lexer.Token
+	Expected string
+	// None if the operator is at the end of the file
+	Got utils.Optional[lexer.Token]
+}
+
+func (e ParseErrorIncompleteOperator) Error() string {
+	got := "EOF"
+
+	if tk, err := e.Got.Value(); err == nil {
+		got = "token " + tk.ToDisplayString()
+	}
+
+	return fmt.Sprintf(
+		"Expected %s after %s, but got %s",
+		e.Expected,
+		e.Operator.ToDisplayString(),
+		got,
+	)
+}
+
+// An operator overload that does not have one or two parameters.
+type ParseErrorOperatorOverloadArity struct {
+	// The backticked name of the overloaded operator, such as `+`
+	Operator   lexer.Token
+	Parameters int
+}
+
+func (e ParseErrorOperatorOverloadArity) Error() string {
+	return fmt.Sprintf(
+		"Operator overload %s must have one or two parameters, but has %d",
+		e.Operator.ToDisplayString(),
+		e.Parameters,
+	)
+}
+
+// A name declared more than once where it may only appear once, such as a generic parameter or the getter of a computed var.
+type ParseErrorDuplicateName struct {
+	Name         lexer.Token
+	WhileParsing ASTNodeKind
+}
+
+func (e ParseErrorDuplicateName) Error() string {
+	return fmt.Sprintf(
+		"Duplicate name %s while parsing node %s",
+		e.Name.ToDisplayString(),
+		e.WhileParsing.ToDisplayString(),
+	)
+}
+
 type ParseErrorUnexpectedEOF struct {
 	WhileParsing ASTNodeKind
 }
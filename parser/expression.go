@@ -0,0 +1,622 @@
+package parser
+
+import (
+	"slices"
+
+	"ljpprojects.org/sqopl/lexer"
+)
+
+// How tightly an operator binds to its operands. Operators with a higher precedence bind more tightly.
+type precedence uint8
+
+// From loosest to tightest:
+//
+//	Precedence  Operators                            Associativity
+//	assignment  = += -= *= /= %= &= |= ^= <<= >>=    right
+//	ternary     cond -> a else b                     right
+//	coalesce    ??                                   right
+//	logicalOr   ||                                   left
+//	logicalAnd  &&                                   left
+//	comparison  == != < > <= >=                      left
+//	bitwiseOr   |                                    left
+//	bitwiseXor  ^                                    left
+//	bitwiseAnd  &                                    left
+//	shift       << >>                                left
+//	additive    + -                                  left
+//	multiply    * / %                                left
+//	cast        is as as?                            left, and the right-hand side is a type
+//	prefix      - ! ~ ++ --                          right
+//	postfix     ++ -- ? . ?. f(label: x)             left
+const (
+	lowestPrecedence precedence = iota
+	assignmentPrecedence
+	ternaryPrecedence
+	coalescePrecedence
+	logicalOrPrecedence
+	logicalAndPrecedence
+	comparisonPrecedence
+	bitwiseOrPrecedence
+	bitwiseXorPrecedence
+	bitwiseAndPrecedence
+	shiftPrecedence
+	additivePrecedence
+	multiplyPrecedence
+	castPrecedence
+	prefixPrecedence
+	postfixPrecedence
+)
+
+type associativity uint8
+
+const (
+	leftAssociative associativity = iota
+	rightAssociative
+)
+
+type infixOperator struct {
+	precedence    precedence
+	associativity associativity
+}
+
+// The operators that go between two operands, apart from "is" and "as", which are keywords.
+var infixOperators = map[lexer.OperatorKind]infixOperator{
+	lexer.EqualsOperatorKind:            {assignmentPrecedence, rightAssociative},
+	lexer.PlusEqualsOperatorKind:        {assignmentPrecedence, rightAssociative},
+	lexer.MinusEqualsOperatorKind:       {assignmentPrecedence, rightAssociative},
+	lexer.AsteriskEqualsOperatorKind:    {assignmentPrecedence, rightAssociative},
+	lexer.SlashEqualsOperatorKind:       {assignmentPrecedence, rightAssociative},
+	lexer.PercentEqualsOperatorKind:     {assignmentPrecedence, rightAssociative},
+	lexer.AmpersandEqualsOperatorKind:   {assignmentPrecedence, rightAssociative},
+	lexer.PipeEqualsOperatorKind:        {assignmentPrecedence, rightAssociative},
+	lexer.CaretEqualsOperatorKind:       {assignmentPrecedence, rightAssociative},
+	lexer.ShiftLeftEqualsOperatorKind:   {assignmentPrecedence, rightAssociative},
+	lexer.ShiftRightEqualsOperatorKind:  {assignmentPrecedence, rightAssociative},
+	lexer.ArrowOperatorKind:             {ternaryPrecedence, rightAssociative},
+	lexer.NullCoalesceOperatorKind:      {coalescePrecedence, rightAssociative},
+	lexer.OrOrOperatorKind:              {logicalOrPrecedence, leftAssociative},
+	lexer.AndAndOperatorKind:            {logicalAndPrecedence, leftAssociative},
+	lexer.EqualsEqualsOperatorKind:      {comparisonPrecedence, leftAssociative},
+	lexer.NotEqualsOperatorKind:         {comparisonPrecedence, leftAssociative},
+	lexer.LessThanOperatorKind:          {comparisonPrecedence, leftAssociative},
+	lexer.GreaterThanOperatorKind:       {comparisonPrecedence, leftAssociative},
+	lexer.LessThanEqualsOperatorKind:    {comparisonPrecedence, leftAssociative},
+	lexer.GreaterThanEqualsOperatorKind: {comparisonPrecedence, leftAssociative},
+	lexer.PipeOperatorKind:              {bitwiseOrPrecedence, leftAssociative},
+	lexer.CaretOperatorKind:             {bitwiseXorPrecedence, leftAssociative},
+	lexer.AmpersandOperatorKind:         {bitwiseAndPrecedence, leftAssociative},
+	lexer.ShiftLeftOperatorKind:         {shiftPrecedence, leftAssociative},
+	lexer.ShiftRightOperatorKind:        {shiftPrecedence, leftAssociative},
+	lexer.PlusOperatorKind:              {additivePrecedence, leftAssociative},
+	lexer.MinusOperatorKind:             {additivePrecedence, leftAssociative},
+	lexer.AsteriskOperatorKind:          {multiplyPrecedence, leftAssociative},
+	lexer.SlashOperatorKind:             {multiplyPrecedence, leftAssociative},
+	lexer.PercentOperatorKind:           {multiplyPrecedence, leftAssociative},
+	lexer.OptionalCastOperatorKind:      {castPrecedence, leftAssociative},
+}
+
+var prefixOperators = []lexer.OperatorKind{
+	lexer.MinusOperatorKind,
+	lexer.BangOperatorKind,
+	lexer.TildeOperatorKind,
+	lexer.IncrementOperatorKind,
+	lexer.DecrementOperatorKind,
+}
+
+var postfixOperators = []lexer.OperatorKind{
+	lexer.IncrementOperatorKind,
+	lexer.DecrementOperatorKind,
+	lexer.QuestionOperatorKind,
+}
+
+// Returns the infix operator that tk is, if it is one.
+func infixOperatorOf(tk lexer.Token) (infixOperator, bool) {
+	switch tk.Keyword() {
+	case lexer.IsKeywordKind, lexer.AsKeywordKind:
+		return infixOperator{castPrecedence, leftAssociative}, true
+	}
+
+	if tk.Kind() != lexer.OperatorTokenKind {
+		return infixOperator{}, false
+	}
+
+	op, ok := infixOperators[tk.Operator()]
+
+	return op, ok
+}
+
+func isOneOfOperators(operators []lexer.OperatorKind) func(lexer.Token) bool {
+	return func(tk lexer.Token) bool {
+		for _, operator := range operators {
+			if isOperator(operator)(tk) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Reports whether an expression can begin with tk.
+func startsExpression(tk lexer.Token) bool {
+	switch tk.Kind() {
+	case lexer.IntegerTokenKind,
+		lexer.DecimalTokenKind,
+		lexer.CharacterTokenKind,
+		lexer.StringTokenKind,
+		lexer.IdentifierTokenKind,
+		lexer.QuotedIdentifierTokenKind:
+		return true
+	}
+
+	return isCharacter('(', lexer.GroupingTokenKind)(tk) || isOneOfOperators(prefixOperators)(tk)
+}
+
+// Parses an expression, using the operator precedences and associativities documented on precedence.
+func (p *Parser) ParseExpression() (Expression, error) {
+	return p.parseExpression(lowestPrecedence)
+}
+
+// Parses an expression whose infix operators all bind more tightly than minPrecedence.
+func (p *Parser) parseExpression(minPrecedence precedence) (Expression, error) {
+	left, err := p.parsePrefixExpression()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		mtk, err := p.PeekToken()
+
+		if err != nil {
+			return nil, err
+		}
+
+		tk, err := mtk.Value()
+
+		if err != nil {
+			return left, nil
+		}
+
+		op, ok := infixOperatorOf(tk)
+
+		if !ok || op.precedence <= minPrecedence {
+			return left, nil
+		}
+
+		if _, err := p.NextToken(); err != nil {
+			return nil, err
+		}
+
+		if left, err = p.parseInfixExpression(left, tk, op); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Parses the right-hand side of an infix operator that has just been consumed.
+func (p *Parser) parseInfixExpression(left Expression, operator lexer.Token, op infixOperator) (Expression, error) {
+	startpos := left.Location().Start
+
+	switch {
+	case operator.Keyword() == lexer.IsKeywordKind,
+		operator.Keyword() == lexer.AsKeywordKind,
+		isOperator(lexer.OptionalCastOperatorKind)(operator):
+		typ, err := p.parseTypeOperand(operator)
+
+		if err != nil {
+			return nil, err
+		}
+
+		loc := lexer.InitLocation(startpos, p.currentPos())
+
+		switch operator.Keyword() {
+		case lexer.IsKeywordKind:
+			return TypeCastableQueryExpressionASTNode{Loc: loc, Value: left, Type: typ}, nil
+		case lexer.AsKeywordKind:
+			return TypeCastExpressionASTNode{Loc: loc, Value: left, Type: typ}, nil
+		}
+
+		return RuntimeTypeCastExpressionASTNode{Loc: loc, Value: left, Type: typ}, nil
+	case isOperator(lexer.ArrowOperatorKind)(operator):
+		// The value on success is delimited by "else", so it can be any expression
+		success, err := p.parseOperand(operator, lowestPrecedence)
+
+		if err != nil {
+			return nil, err
+		}
+
+		mtk, err := p.PeekToken()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if tk, err := mtk.Value(); err != nil || tk.Keyword() != lexer.ElseKeywordKind {
+			return nil, ParseErrorIncompleteOperator{
+				Operator: operator,
+				Expected: `"else"`,
+				Got:      mtk,
+			}
+		}
+
+		if _, err := p.NextToken(); err != nil {
+			return nil, err
+		}
+
+		fallback, err := p.parseOperand(operator, op.precedence-1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return TernaryExpressionASTNode{
+			Loc:           lexer.InitLocation(startpos, p.currentPos()),
+			Condition:     left,
+			SuccessValue:  success,
+			FallbackValue: fallback,
+		}, nil
+	}
+
+	minPrecedence := op.precedence
+
+	if op.associativity == rightAssociative {
+		minPrecedence--
+	}
+
+	right, err := p.parseOperand(operator, minPrecedence)
+
+	if err != nil {
+		return nil, err
+	}
+
+	loc := lexer.InitLocation(startpos, p.currentPos())
+
+	if op.precedence == assignmentPrecedence {
+		return AssignmentStatementASTNode{
+			Loc:      loc,
+			Operator: operator.Operator(),
+			Left:     left,
+			Right:    right,
+		}, nil
+	}
+
+	if isOperator(lexer.NullCoalesceOperatorKind)(operator) {
+		return NullCoalesceExpressionASTNode{
+			Loc:           loc,
+			Value:         left,
+			FallbackValue: right,
+		}, nil
+	}
+
+	return BinaryExpressionASTNode{
+		Loc:      loc,
+		Operator: operator.Operator(),
+		Left:     left,
+		Right:    right,
+	}, nil
+}
+
+// Parses the operand after operator, reporting the operator if there is no operand.
+func (p *Parser) parseOperand(operator lexer.Token, minPrecedence precedence) (Expression, error) {
+	mtk, err := p.PeekToken()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tk, err := mtk.Value(); err != nil || !startsExpression(tk) {
+		return nil, ParseErrorIncompleteOperator{
+			Operator: operator,
+			Expected: "an expression",
+			Got:      mtk,
+		}
+	}
+
+	return p.parseExpression(minPrecedence)
+}
+
+// Parses the type after "is", "as" or "as?", reporting the operator if there is no type.
+func (p *Parser) parseTypeOperand(operator lexer.Token) (Type, error) {
+	mtk, err := p.PeekToken()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := mtk.Value(); err != nil {
+		return nil, ParseErrorIncompleteOperator{
+			Operator: operator,
+			Expected: "a type",
+			Got:      mtk,
+		}
+	}
+
+	return p.parseCastType()
+}
+
+// Parses prefix operators, then the primary expression they apply to and any postfix operators after it.
+func (p *Parser) parsePrefixExpression() (Expression, error) {
+	tk, err := p.requirePeekToken(PrefixUnaryExpressionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !isOneOfOperators(prefixOperators)(tk) {
+		return p.parsePostfixExpression()
+	}
+
+	if _, err := p.NextToken(); err != nil {
+		return nil, err
+	}
+
+	right, err := p.parseOperand(tk, prefixPrecedence)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return PrefixUnaryExpressionASTNode{
+		Loc:      lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		Operator: tk.Operator(),
+		Right:    right,
+	}, nil
+}
+
+// Parses a primary expression followed by any postfix operators, member accesses and calls, which all bind equally tightly.
+func (p *Parser) parsePostfixExpression() (Expression, error) {
+	left, err := p.parsePrimaryExpression()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		mtk, err := p.PeekToken()
+
+		if err != nil {
+			return nil, err
+		}
+
+		tk, err := mtk.Value()
+
+		if err != nil {
+			return left, nil
+		}
+
+		switch {
+		case isOperator(lexer.DotOperatorKind)(tk), isOperator(lexer.OptionalChainOperatorKind)(tk):
+			if _, err := p.NextToken(); err != nil {
+				return nil, err
+			}
+
+			if left, err = p.parseMemberAccess(left, tk); err != nil {
+				return nil, err
+			}
+
+			continue
+		case isCharacter('(', lexer.GroupingTokenKind)(tk):
+			if _, err := p.NextToken(); err != nil {
+				return nil, err
+			}
+
+			if left, err = p.parseCall(left); err != nil {
+				return nil, err
+			}
+
+			continue
+		case !isOneOfOperators(postfixOperators)(tk):
+			return left, nil
+		}
+
+		if _, err := p.NextToken(); err != nil {
+			return nil, err
+		}
+
+		loc := lexer.InitLocation(left.Location().Start, p.currentPos())
+
+		if tk.Operator() == lexer.QuestionOperatorKind {
+			left = BubbleValueToReturnASTNode{
+				Loc:   loc,
+				Value: left,
+			}
+		} else {
+			left = PostfixUnaryExpressionASTNode{
+				Loc:      loc,
+				Operator: tk.Operator(),
+				Left:     left,
+			}
+		}
+	}
+}
+
+// Parses the name of the member after '.' or "?.", which has just been consumed, such as the number in self.number.
+// Accessing a member of a member access adds to it, so a.b.c is one MemberExpressionASTNode.
+func (p *Parser) parseMemberAccess(object Expression, operator lexer.Token) (Expression, error) {
+	mtk, err := p.PeekToken()
+
+	if err != nil {
+		return nil, err
+	}
+
+	tk, err := mtk.Value()
+
+	if err != nil || (tk.Kind() != lexer.IdentifierTokenKind && tk.Kind() != lexer.QuotedIdentifierTokenKind) {
+		return nil, ParseErrorIncompleteOperator{
+			Operator: operator,
+			Expected: "a member name",
+			Got:      mtk,
+		}
+	}
+
+	if _, err := p.NextToken(); err != nil {
+		return nil, err
+	}
+
+	member := IdentifierLiteralASTNode{
+		Loc:  lexer.InitLocation(tk.Startpos(), tk.Endpos()),
+		Name: tk.IdentifierName(),
+	}
+
+	loc := lexer.InitLocation(object.Location().Start, p.currentPos())
+
+	if operator.Operator() == lexer.OptionalChainOperatorKind {
+		if chain, ok := object.(OptionalChainingASTNode); ok {
+			return OptionalChainingASTNode{Loc: loc, Chain: append(slices.Clip(chain.Chain), member)}, nil
+		}
+
+		return OptionalChainingASTNode{Loc: loc, Chain: []Expression{object, member}}, nil
+	}
+
+	if access, ok := object.(MemberExpressionASTNode); ok {
+		return MemberExpressionASTNode{Loc: loc, Segments: append(slices.Clip(access.Segments), member)}, nil
+	}
+
+	return MemberExpressionASTNode{Loc: loc, Segments: []Expression{object, member}}, nil
+}
+
+// Parses a call of callee, whose '(' has just been consumed, up to and including the ')'.
+func (p *Parser) parseCall(callee Expression) (Expression, error) {
+	arguments, err := p.parseCallArguments()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return FunctionCallExpressionASTNode{
+		Loc:       lexer.InitLocation(callee.Location().Start, p.currentPos()),
+		Callee:    callee,
+		Arguments: arguments,
+		Generics:  map[string]TypeGenericASTNode{},
+	}, nil
+}
+
+// Parses the arguments of a call up to and including its ')'.
+// Every argument is labelled, as in Integer32(str: str), and a call without arguments may be written f(:).
+// A trailing ',' is allowed.
+func (p *Parser) parseCallArguments() ([]CallArgument, error) {
+	arguments := []CallArgument{}
+
+	empty, err := p.acceptMatching(isCharacter(':', lexer.SeparatorTokenKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if empty {
+		mtk, err := p.ExpectCharacter(')', lexer.GroupingTokenKind)
+
+		if _, err := requireToken(mtk, err, FunctionCallExpressionASTNodeKind); err != nil {
+			return nil, err
+		}
+
+		return arguments, nil
+	}
+
+	for {
+		closed, err := p.acceptMatching(isCharacter(')', lexer.GroupingTokenKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if closed {
+			return arguments, nil
+		}
+
+		mtk, err := p.ExpectIdentifier()
+		labelTk, err := requireToken(mtk, err, FunctionCallExpressionASTNodeKind)
+
+		if err != nil {
+			return nil, err
+		}
+
+		mtk, err = p.ExpectCharacter(':', lexer.SeparatorTokenKind)
+
+		if _, err := requireToken(mtk, err, FunctionCallExpressionASTNodeKind); err != nil {
+			return nil, err
+		}
+
+		value, err := p.ParseExpression()
+
+		if err != nil {
+			return nil, err
+		}
+
+		arguments = append(arguments, CallArgument{
+			Loc:   lexer.InitLocation(labelTk.Startpos(), p.currentPos()),
+			Label: labelTk.IdentifierName(),
+			Value: value,
+		})
+
+		separated, err := p.acceptMatching(isCharacter(',', lexer.SeparatorTokenKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !separated {
+			mtk, err := p.ExpectCharacter(')', lexer.GroupingTokenKind)
+
+			if _, err := requireToken(mtk, err, FunctionCallExpressionASTNodeKind); err != nil {
+				return nil, err
+			}
+
+			return arguments, nil
+		}
+	}
+}
+
+// Parses a literal, an identifier or a parenthesised expression.
+func (p *Parser) parsePrimaryExpression() (Expression, error) {
+	tk, err := p.requirePeekToken(BinaryExpressionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch tk.Kind() {
+	case lexer.IntegerTokenKind:
+		return p.ParseIntegerLiteral()
+	case lexer.DecimalTokenKind:
+		return p.ParseDecimalLiteral()
+	case lexer.CharacterTokenKind:
+		return p.ParseCharacterLiteral()
+	case lexer.StringTokenKind:
+		return p.ParseStringLiteral()
+	case lexer.IdentifierTokenKind, lexer.QuotedIdentifierTokenKind:
+		if _, err := p.NextToken(); err != nil {
+			return nil, err
+		}
+
+		return IdentifierLiteralASTNode{
+			Loc:  lexer.InitLocation(tk.Startpos(), tk.Endpos()),
+			Name: tk.IdentifierName(),
+		}, nil
+	}
+
+	if !isCharacter('(', lexer.GroupingTokenKind)(tk) {
+		return nil, ParseErrorUnexpectedToken{
+			Got:          tk,
+			WhileParsing: BinaryExpressionASTNodeKind,
+		}
+	}
+
+	if _, err := p.NextToken(); err != nil {
+		return nil, err
+	}
+
+	inner, err := p.parseOperand(tk, lowestPrecedence)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mtk, err := p.ExpectCharacter(')', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, BinaryExpressionASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	return inner, nil
+}
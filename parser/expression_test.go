@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Writes out an expression with every operation in parentheses, such as (1 + (2 * 3)), so that tests can check how it was grouped.
+func describeExpression(e Expression) string {
+	switch e := e.(type) {
+	case IdentifierLiteralASTNode:
+		return e.Name
+	case IntegerLiteralASTNode:
+		return e.Value.String()
+	case BinaryExpressionASTNode:
+		return fmt.Sprintf("(%s %s %s)", describeExpression(e.Left), e.Operator.Spelling(), describeExpression(e.Right))
+	case PrefixUnaryExpressionASTNode:
+		return fmt.Sprintf("(%s%s)", e.Operator.Spelling(), describeExpression(e.Right))
+	case PostfixUnaryExpressionASTNode:
+		return fmt.Sprintf("(%s%s)", describeExpression(e.Left), e.Operator.Spelling())
+	case BubbleValueToReturnASTNode:
+		return fmt.Sprintf("(%s?)", describeExpression(e.Value))
+	case NullCoalesceExpressionASTNode:
+		return fmt.Sprintf("(%s ?? %s)", describeExpression(e.Value), describeExpression(e.FallbackValue))
+	case TernaryExpressionASTNode:
+		return fmt.Sprintf("(%s -> %s else %s)", describeExpression(e.Condition), describeExpression(e.SuccessValue), describeExpression(e.FallbackValue))
+	case TypeCastableQueryExpressionASTNode:
+		return fmt.Sprintf("(%s is %s)", describeExpression(e.Value), describeType(e.Type))
+	case TypeCastExpressionASTNode:
+		return fmt.Sprintf("(%s as %s)", describeExpression(e.Value), describeType(e.Type))
+	case RuntimeTypeCastExpressionASTNode:
+		return fmt.Sprintf("(%s as? %s)", describeExpression(e.Value), describeType(e.Type))
+	case AssignmentStatementASTNode:
+		return fmt.Sprintf("(%s %s %s)", describeExpression(e.Left), e.Operator.Spelling(), describeExpression(e.Right))
+	case MemberExpressionASTNode:
+		return describeExpressions(e.Segments, ".")
+	case OptionalChainingASTNode:
+		return describeExpressions(e.Chain, "?.")
+	case FunctionCallExpressionASTNode:
+		if len(e.Arguments) == 0 {
+			return describeExpression(e.Callee) + "(:)"
+		}
+
+		arguments := []string{}
+
+		for _, argument := range e.Arguments {
+			arguments = append(arguments, argument.Label+": "+describeExpression(argument.Value))
+		}
+
+		return fmt.Sprintf("%s(%s)", describeExpression(e.Callee), strings.Join(arguments, ", "))
+	}
+
+	return fmt.Sprintf("<%T>", e)
+}
+
+func describeExpressions(expressions []Expression, separator string) string {
+	described := []string{}
+
+	for _, e := range expressions {
+		described = append(described, describeExpression(e))
+	}
+
+	return strings.Join(described, separator)
+}
+
+func TestExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))"},
+		{"1 - 2 - 3", "((1 - 2) - 3)"},
+		{"a ?? b ?? c", "(a ?? (b ?? c))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"1 << 2 + 3", "(1 << (2 + 3))"},
+		{"-a * b", "((-a) * b)"},
+		{"-a++", "(-(a++))"},
+		{"a? + b", "((a?) + b)"},
+		{"a -> b else c -> d else e", "(a -> b else (c -> d else e))"},
+		{"(1 + 2) * 3", "((1 + 2) * 3)"},
+		{"a as Integer64 * b", "((a as Integer64) * b)"},
+		{"a as Integer64 < b", "((a as Integer64) < b)"},
+		{"a as core:Integer64 < b", "((a as core:Integer64) < b)"},
+		{"a as List<T> < b", "((a as List<T>) < b)"},
+		{"a as Map<K, List<V>> == b", "((a as Map<K, List<V>>) == b)"},
+		{"a as List<T>> b", "((a as List<T>) > b)"},
+		{"a as List<T>>= b", "((a as List<T>) >= b)"},
+		{"a is T | b", "((a is T) | b)"},
+		{"a is (T | U) | b", "((a is (T | U)) | b)"},
+		{"a as? T? ?? b", "((a as? T?) ?? b)"},
+		{"x = y * z", "(x = (y * z))"},
+		{"a = b = c", "(a = (b = c))"},
+		{"a += b -> c else d", "(a += (b -> c else d))"},
+		{"a <<= b ?? c", "(a <<= (b ?? c))"},
+		{"self.number = number", "(self.number = number)"},
+		{"a.b.c * 2", "(a.b.c * 2)"},
+		{"-a.b", "(-a.b)"},
+		{"(a + b).c", "(a + b).c"},
+		{"a?.b?.c", "a?.b?.c"},
+		{"a?.b.c", "a?.b.c"},
+		{"val.ConvertToInteger32(:)", "val.ConvertToInteger32(:)"},
+		{"f()", "f(:)"},
+		{"Integer32(str: str)?", "(Integer32(str: str)?)"},
+		{"f(a: 1, b: 2 + 3,)", "f(a: 1, b: (2 + 3))"},
+		{"f(a: 1)(b: 2).c", "f(a: 1)(b: 2).c"},
+	}
+
+	for _, test := range tests {
+		expr, err := newTestParser(test.source).ParseExpression()
+
+		if err != nil {
+			t.Errorf("parsing %q: %v", test.source, err)
+			continue
+		}
+
+		if got := describeExpression(expr); got != test.want {
+			t.Errorf("parsing %q: got %s, want %s", test.source, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package parser
+
+import (
+	"ljpprojects.org/sqopl/lexer"
+	"ljpprojects.org/sqopl/utils"
+)
+
+// Parses a function definition, which is one of:
+//
+//	"fn" Name [Generics] [Parameters] ["->" Type] (Block | [";"])   a function, or a prototype if it has no body
+//	"fn" Name "." Name [Generics] [Parameters] ["->" Type] Block     a method of the named type
+//	"fn" Name "." "`" Operator "`" Parameters ["->" Type] Block      an operator overload for the named type
+//
+// Functions without any parameters may leave out the parentheses, as in fn memory -> Data { ... }.
+func (p *Parser) ParseFunctionDefinition() (Definition, error) {
+	mtk, err := p.ExpectKeyword(lexer.FnKeywordKind)
+	tk, err := requireToken(mtk, err, FunctionDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	startpos := tk.Startpos()
+
+	mtk, err = p.ExpectIdentifier()
+	nameTk, err := requireToken(mtk, err, FunctionDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	isMethod, err := p.acceptMatching(isOperator(lexer.DotOperatorKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isMethod {
+		return p.parseMethodDefinition(startpos, NamedTypeASTNode{
+			Loc:              lexer.InitLocation(nameTk.Startpos(), nameTk.Endpos()),
+			Module:           []string{},
+			Name:             nameTk.IdentifierName(),
+			GenericArguments: []Type{},
+		})
+	}
+
+	generics, err := p.parseGenericParameters()
+
+	if err != nil {
+		return nil, err
+	}
+
+	parameters, err := p.parseOptionalFunctionParameters(FunctionDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	returnType, err := p.parseReturnType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	body := utils.NoneOptional[BlockASTNode]()
+
+	hasBody, err := p.peekMatches(isCharacter('{', lexer.GroupingTokenKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if hasBody {
+		block, err := p.ParseBlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		body = utils.SomeOptional(block)
+	} else if _, err := p.acceptMatching(isCharacter(';', lexer.SeparatorTokenKind)); err != nil {
+		return nil, err
+	}
+
+	return FunctionDefinitionASTNode{
+		Loc:        lexer.InitLocation(startpos, p.currentPos()),
+		Name:       nameTk.IdentifierName(),
+		ReturnType: returnType,
+		Parameters: parameters,
+		Generics:   generics,
+		Body:       body,
+	}, nil
+}
+
+// Parses the rest of a method definition or operator overload, after the '.' following the type it belongs to.
+func (p *Parser) parseMethodDefinition(startpos lexer.Position, contextType Type) (Definition, error) {
+	tk, err := p.requirePeekToken(MethodDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tk.Kind() == lexer.QuotedIdentifierTokenKind && tk.Operator() != lexer.NotAnOperatorKind {
+		return p.parseOperatorOverload(startpos, contextType)
+	}
+
+	mtk, err := p.ExpectIdentifier()
+	nameTk, err := requireToken(mtk, err, MethodDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	generics, err := p.parseGenericParameters()
+
+	if err != nil {
+		return nil, err
+	}
+
+	parameters, err := p.parseOptionalFunctionParameters(MethodDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	returnType, err := p.parseReturnType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.ParseBlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return MethodDefinitionASTNode{
+		Loc:         lexer.InitLocation(startpos, p.currentPos()),
+		Name:        nameTk.IdentifierName(),
+		ReturnType:  returnType,
+		Parameters:  parameters,
+		ContextType: contextType,
+		Generics:    generics,
+		Body:        body,
+	}, nil
+}
+
+func (p *Parser) parseOperatorOverload(startpos lexer.Position, contextType Type) (Definition, error) {
+	operatorTk, err := p.requirePeekToken(OperatorOverloadASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	operator, err := p.ExpectOperatorName()
+
+	if err != nil {
+		return nil, err
+	}
+
+	parameters, err := p.parseFunctionParameters(OperatorOverloadASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parameters) != 1 && len(parameters) != 2 {
+		return nil, ParseErrorOperatorOverloadArity{
+			Operator:   operatorTk,
+			Parameters: len(parameters),
+		}
+	}
+
+	var rightHandType Type
+
+	if len(parameters) == 2 {
+		rightHandType = parameters[1].Type
+	}
+
+	returnType, err := p.parseReturnType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.ParseBlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return OperatorOverloadASTNode{
+		Loc:           lexer.InitLocation(startpos, p.currentPos()),
+		Operator:      operator,
+		ReturnType:    returnType,
+		Parameters:    parameters,
+		ContextType:   contextType,
+		RightHandType: rightHandType,
+		Body:          body,
+	}, nil
+}
+
+// Parses the generic parameters of a function if there are any, such as <T, U: Comparable & Hashable>, keeping them in order.
+// Each parameter may be followed by the interfaces that it must conform to.
+func (p *Parser) parseGenericParameters() ([]TypeGenericASTNode, error) {
+	generics := []TypeGenericASTNode{}
+
+	hasGenerics, err := p.acceptMatching(isOperator(lexer.LessThanOperatorKind))
+
+	if err != nil || !hasGenerics {
+		return generics, err
+	}
+
+	err = p.parseAngleBracketList(NamedTypeASTNodeKind, func() error {
+		mtk, err := p.ExpectIdentifier()
+		tk, err := requireToken(mtk, err, NamedTypeASTNodeKind)
+
+		if err != nil {
+			return err
+		}
+
+		for _, generic := range generics {
+			if generic.Name == tk.IdentifierName() {
+				return ParseErrorDuplicateName{
+					Name:         tk,
+					WhileParsing: NamedTypeASTNodeKind,
+				}
+			}
+		}
+
+		generic := TypeGenericASTNode{
+			Name:       tk.IdentifierName(),
+			ConformsTo: []NamedTypeASTNode{},
+		}
+
+		constrained, err := p.acceptMatching(isCharacter(':', lexer.SeparatorTokenKind))
+
+		if err != nil {
+			return err
+		}
+
+		for constrained {
+			conformsTo, err := p.ParseNamedType()
+
+			if err != nil {
+				return err
+			}
+
+			generic.ConformsTo = append(generic.ConformsTo, conformsTo)
+
+			if constrained, err = p.acceptMatching(isOperator(lexer.AmpersandOperatorKind)); err != nil {
+				return err
+			}
+		}
+
+		generic.Loc = lexer.InitLocation(tk.Startpos(), p.currentPos())
+		generics = append(generics, generic)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return generics, nil
+}
+
+// Parses a parenthesised parameter list if there is one, and otherwise returns no parameters.
+func (p *Parser) parseOptionalFunctionParameters(whileParsing ASTNodeKind) ([]FunctionParameter, error) {
+	hasParameters, err := p.peekMatches(isCharacter('(', lexer.GroupingTokenKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasParameters {
+		return []FunctionParameter{}, nil
+	}
+
+	return p.parseFunctionParameters(whileParsing)
+}
+
+// Parses a parenthesised parameter list, such as (lhs Foo, rhs Foo), keeping the parameters in order.
+func (p *Parser) parseFunctionParameters(whileParsing ASTNodeKind) ([]FunctionParameter, error) {
+	mtk, err := p.ExpectCharacter('(', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, whileParsing); err != nil {
+		return nil, err
+	}
+
+	return p.parseParameterList(whileParsing, []FunctionParameter{})
+}
+
+// Parses the rest of a parameter list up to and including its ')', appending each parameter to parameters.
+func (p *Parser) parseParameterList(whileParsing ASTNodeKind, parameters []FunctionParameter) ([]FunctionParameter, error) {
+	for {
+		closed, err := p.acceptMatching(isCharacter(')', lexer.GroupingTokenKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if closed {
+			return parameters, nil
+		}
+
+		mtk, err := p.ExpectIdentifier()
+		tk, err := requireToken(mtk, err, whileParsing)
+
+		if err != nil {
+			return nil, err
+		}
+
+		paramType, err := p.ParseType()
+
+		if err != nil {
+			return nil, err
+		}
+
+		parameters = append(parameters, FunctionParameter{
+			Loc:  lexer.InitLocation(tk.Startpos(), p.currentPos()),
+			Name: tk.IdentifierName(),
+			Type: paramType,
+		})
+
+		separated, err := p.acceptMatching(isCharacter(',', lexer.SeparatorTokenKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !separated {
+			mtk, err := p.ExpectCharacter(')', lexer.GroupingTokenKind)
+
+			if _, err := requireToken(mtk, err, whileParsing); err != nil {
+				return nil, err
+			}
+
+			return parameters, nil
+		}
+	}
+}
+
+// Parses "->" followed by a return type if it comes next, and otherwise returns nil.
+func (p *Parser) parseReturnType() (Type, error) {
+	hasReturnType, err := p.acceptMatching(isOperator(lexer.ArrowOperatorKind))
+
+	if err != nil || !hasReturnType {
+		return nil, err
+	}
+
+	return p.ParseType()
+}
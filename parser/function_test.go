@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"ljpprojects.org/sqopl/lexer"
+)
+
+func parseFunction(t *testing.T, source string) FunctionDefinitionASTNode {
+	t.Helper()
+
+	def, err := newTestParser(source).ParseFunctionDefinition()
+
+	if err != nil {
+		t.Fatalf("parsing %q: %v", source, err)
+	}
+
+	fn, ok := def.(FunctionDefinitionASTNode)
+
+	if !ok {
+		t.Fatalf("parsing %q: got a %T, want a FunctionDefinitionASTNode", source, def)
+	}
+
+	return fn
+}
+
+func TestParseReturnStatements(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"fn Main() -> Integer32 { return 0; }", "0"},
+		{"fn Sum(a Integer32, b Integer32) -> Integer32 { return a + b; }", "(a + b)"},
+		{"fn Stop { return; }", ""},
+	}
+
+	for _, test := range tests {
+		fn := parseFunction(t, test.source)
+		body, err := fn.Body.Value()
+
+		if err != nil || len(body.Code) != 1 {
+			t.Errorf("parsing %q: want a body with one statement", test.source)
+			continue
+		}
+
+		ret, ok := body.Code[0].(ExplicitReturnASTNode)
+
+		if !ok {
+			t.Errorf("parsing %q: got a %T, want an ExplicitReturnASTNode", test.source, body.Code[0])
+			continue
+		}
+
+		got := ""
+
+		if value, err := ret.Value.Value(); err == nil {
+			got = describeExpression(value)
+		}
+
+		if got != test.want {
+			t.Errorf("parsing %q: returns %q, want %q", test.source, got, test.want)
+		}
+	}
+}
+
+func TestUnsupportedStatementKeyword(t *testing.T) {
+	_, err := newTestParser("fn Main { defer x; }").ParseFunctionDefinition()
+
+	var unexpected ParseErrorUnexpectedToken
+
+	if !errors.As(err, &unexpected) {
+		t.Fatalf("got error %v, want a ParseErrorUnexpectedToken", err)
+	}
+
+	if unexpected.Got.Keyword() != lexer.DeferKeywordKind {
+		t.Errorf("got unexpected token %s, want defer", unexpected.Got.ToDisplayString())
+	}
+}
+
+func TestParseGenericParameters(t *testing.T) {
+	fn := parseFunction(t, "fn Convert<To, From: Comparable & Hashable, Via>(value From) -> To;")
+
+	names := []string{}
+
+	for _, generic := range fn.Generics {
+		names = append(names, generic.Name)
+	}
+
+	if got := strings.Join(names, ", "); got != "To, From, Via" {
+		t.Fatalf("got generics %s, want To, From, Via in declaration order", got)
+	}
+
+	conformsTo := []string{}
+
+	for _, named := range fn.Generics[1].ConformsTo {
+		conformsTo = append(conformsTo, named.Name)
+	}
+
+	if got := strings.Join(conformsTo, " & "); got != "Comparable & Hashable" {
+		t.Errorf("From conforms to %s, want Comparable & Hashable", got)
+	}
+}
+
+func TestDuplicateGenericParameter(t *testing.T) {
+	_, err := newTestParser("fn Swap<T, U, T>(a T, b U);").ParseFunctionDefinition()
+
+	var duplicate ParseErrorDuplicateName
+
+	if !errors.As(err, &duplicate) {
+		t.Fatalf("got error %v, want a ParseErrorDuplicateName", err)
+	}
+
+	if duplicate.Name.IdentifierName() != "T" || duplicate.Name.Startpos().Column() != 15 {
+		t.Errorf("got duplicate %s, want the second T", duplicate.Name.ToDisplayString())
+	}
+}
+
+func TestOperatorOverloadArity(t *testing.T) {
+	_, err := newTestParser("fn Foo.`+`(a Foo, b Foo, c Foo) -> Foo { a }").ParseFunctionDefinition()
+
+	var arity ParseErrorOperatorOverloadArity
+
+	if !errors.As(err, &arity) {
+		t.Fatalf("got error %v, want a ParseErrorOperatorOverloadArity", err)
+	}
+
+	if arity.Parameters != 3 || arity.Operator.Operator() != lexer.PlusOperatorKind || arity.Operator.Startpos().Column() != 8 {
+		t.Errorf("got %v, want three parameters for the `+` at column 8", arity)
+	}
+}
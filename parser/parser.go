@@ -1,8 +1,8 @@
 package parser
 
 import (
-	"fmt"
-	"slices"
+	"strconv"
+	"strings"
 
 	"ljpprojects.org/sqopl/lexer"
 	"ljpprojects.org/sqopl/utils"
@@ -10,6 +10,10 @@ import (
 
 type Parser struct {
 	lexer *lexer.Lexer
+
+	// The rest of an operator token that was split, which comes before every token still in the lexer.
+	// For example, when ">>" closes a list of generics, its second '>' is left here for whatever comes next.
+	pending utils.Optional[lexer.Token]
 }
 
 func NewParser(lexer *lexer.Lexer) *Parser {
@@ -21,14 +25,68 @@ func NewParser(lexer *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) NextToken() (utils.Optional[lexer.Token], error) {
+	if _, err := p.pending.Value(); err == nil {
+		tk := p.pending
+		p.pending = utils.NoneOptional[lexer.Token]()
+
+		return tk, nil
+	}
+
 	return p.lexer.NextToken()
 }
 
 func (p *Parser) PeekToken() (utils.Optional[lexer.Token], error) {
-	return p.lexer.PeekToken()
+	return p.PeekN(1)
+}
+
+func (p *Parser) PeekN(n int) (utils.Optional[lexer.Token], error) {
+	if _, err := p.pending.Value(); err == nil {
+		if n == 1 {
+			return p.pending, nil
+		}
+
+		return p.lexer.PeekN(n - 1)
+	}
+
+	return p.lexer.PeekN(n)
+}
+
+// Returns the position just after the last token consumed, which is the start of the pending rest of a split token if there is one.
+func (p *Parser) currentPos() lexer.Position {
+	if tk, err := p.pending.Value(); err == nil {
+		return tk.Startpos()
+	}
+
+	return p.lexer.CurrentPos()
+}
+
+// A position in the token stream saved by Parser.Mark, along with the parser state that depends on it.
+type ParserMark struct {
+	lexer   lexer.LexerMark
+	pending utils.Optional[lexer.Token]
+}
+
+// Saves the current position in the token stream, for parse paths that may need to backtrack.
+// The mark must be released with either Parser.Rewind or Parser.Commit.
+func (p *Parser) Mark() ParserMark {
+	return ParserMark{
+		lexer:   p.lexer.Mark(),
+		pending: p.pending,
+	}
 }
 
-func (p *Parser) ExpectCharacter(char rune, ofGroup *lexer.TokenGroup) (utils.Optional[lexer.Token], error) {
+// Returns to a position saved with Parser.Mark and releases the mark.
+func (p *Parser) Rewind(mark ParserMark) {
+	p.lexer.Rewind(mark.lexer)
+	p.pending = mark.pending
+}
+
+// Releases a mark without rewinding.
+func (p *Parser) Commit(mark ParserMark) {
+	p.lexer.Commit(mark.lexer)
+}
+
+func (p *Parser) ExpectCharacter(char rune, ofKind lexer.TokenKind) (utils.Optional[lexer.Token], error) {
 	mtk, err := p.NextToken()
 
 	if err != nil {
@@ -41,12 +99,12 @@ func (p *Parser) ExpectCharacter(char rune, ofGroup *lexer.TokenGroup) (utils.Op
 		return utils.NoneOptional[lexer.Token](), nil
 	}
 
-	if slices.Compare(*ofGroup, *tk.Group()) != 0 || char != []rune(tk.Characters())[0] {
+	if tk.Kind() != ofKind || string(char) != tk.Characters() {
 		return utils.NoneOptional[lexer.Token](), ParseErrorExpectedCharacter{
-			Expected:      char,
-			ExpectedGroup: ofGroup,
-			Got:           []rune(tk.Characters())[0],
-			GotGroup:      tk.Group(),
+			Expected:     char,
+			ExpectedKind: ofKind,
+			Got:          []rune(tk.Characters())[0],
+			GotKind:      tk.Kind(),
 		}
 	}
 
@@ -66,7 +124,7 @@ func (p *Parser) ExpectToken(expect lexer.Token) (utils.Optional[lexer.Token], e
 		return utils.NoneOptional[lexer.Token](), nil
 	}
 
-	if expect.Group() != tk.Group() || expect.Characters() != tk.Characters() {
+	if expect.Kind() != tk.Kind() || expect.Characters() != tk.Characters() {
 		return utils.NoneOptional[lexer.Token](), ParseErrorExpectedToken{
 			Expected: expect,
 			Got:      tk,
@@ -76,7 +134,7 @@ func (p *Parser) ExpectToken(expect lexer.Token) (utils.Optional[lexer.Token], e
 	return utils.SomeOptional(tk), nil
 }
 
-func (p *Parser) ExpectTokenOfGroup(expectGroup *lexer.TokenGroup) (utils.Optional[lexer.Token], error) {
+func (p *Parser) ExpectTokenOfKind(expectKind lexer.TokenKind) (utils.Optional[lexer.Token], error) {
 	mtk, err := p.NextToken()
 
 	if err != nil {
@@ -89,9 +147,9 @@ func (p *Parser) ExpectTokenOfGroup(expectGroup *lexer.TokenGroup) (utils.Option
 		return utils.NoneOptional[lexer.Token](), nil
 	}
 
-	if tk.Group() != expectGroup {
+	if tk.Kind() != expectKind {
 		return utils.NoneOptional[lexer.Token](), ParseErrorExpectedToken{
-			Expected: lexer.InitToken(expectGroup, "[ANYTHING]", lexer.Location{}),
+			Expected: lexer.InitToken(expectKind, "[ANYTHING]", lexer.Location{}),
 			Got:      tk,
 		}
 	}
@@ -99,122 +157,556 @@ func (p *Parser) ExpectTokenOfGroup(expectGroup *lexer.TokenGroup) (utils.Option
 	return utils.SomeOptional(tk), nil
 }
 
-func (p *Parser) ParseNamedType() (NamedTypeASTNode, error) {
-	mtk, err := p.ExpectToken(lexer.InitToken(&lexer.TokenIdentifierGroup, "import", lexer.Location{}))
+func (p *Parser) ExpectKeyword(keyword lexer.KeywordKind) (utils.Optional[lexer.Token], error) {
+	return p.ExpectToken(lexer.InitKeywordToken(keyword, lexer.Location{}))
+}
+
+func (p *Parser) ExpectOperator(operator lexer.OperatorKind) (utils.Optional[lexer.Token], error) {
+	return p.ExpectToken(lexer.InitOperatorToken(operator, lexer.Location{}))
+}
+
+// Expects a plain or backtick-quoted identifier. Use Token.IdentifierName to get the name it refers to.
+func (p *Parser) ExpectIdentifier() (utils.Optional[lexer.Token], error) {
+	mtk, err := p.NextToken()
 
 	if err != nil {
-		return NamedTypeASTNode{}, err
+		return utils.NoneOptional[lexer.Token](), err
 	}
 
 	tk, err := mtk.Value()
 
 	if err != nil {
-		return NamedTypeASTNode{}, ParseErrorUnexpectedEOF{
-			WhileParsing: ImportStatementASTNodeKind,
+		return utils.NoneOptional[lexer.Token](), nil
+	}
+
+	if tk.Kind() != lexer.IdentifierTokenKind && tk.Kind() != lexer.QuotedIdentifierTokenKind {
+		return utils.NoneOptional[lexer.Token](), ParseErrorExpectedToken{
+			Expected: lexer.InitToken(lexer.IdentifierTokenKind, "[ANYTHING]", lexer.Location{}),
+			Got:      tk,
 		}
 	}
 
-	startpos := tk.Startpos()
+	return utils.SomeOptional(tk), nil
+}
 
-	mtk, err = p.ExpectTokenOfGroup(&lexer.TokenIdentifierGroup)
+// Expects the name of an overloaded operator, which is the operator's spelling in backticks, such as `+`.
+func (p *Parser) ExpectOperatorName() (lexer.OperatorKind, error) {
+	mtk, err := p.ExpectTokenOfKind(lexer.QuotedIdentifierTokenKind)
 
 	if err != nil {
-		return NamedTypeASTNode{}, err
+		return lexer.NotAnOperatorKind, err
 	}
 
-	tk, err = mtk.Value()
+	tk, err := mtk.Value()
 
 	if err != nil {
-		return NamedTypeASTNode{}, ParseErrorUnexpectedEOF{
-			WhileParsing: ImportStatementASTNodeKind,
+		return lexer.NotAnOperatorKind, ParseErrorUnexpectedEOF{
+			WhileParsing: OperatorOverloadASTNodeKind,
 		}
 	}
 
-	return NamedTypeASTNode{
-		Loc:      lexer.InitLocation(startpos, p.lexer.CurrentPos()),
-		Name:     tk.Characters(),
-		Generics: map[string]TypeGenericASTNode{},
+	if tk.Operator() == lexer.NotAnOperatorKind {
+		return lexer.NotAnOperatorKind, ParseErrorExpectedOperatorName{
+			Got: tk,
+		}
+	}
+
+	return tk.Operator(), nil
+}
+
+// Unwraps a token from one of the Expect methods, treating EOF as an error while parsing a node of the given kind.
+func requireToken(mtk utils.Optional[lexer.Token], err error, whileParsing ASTNodeKind) (lexer.Token, error) {
+	if err != nil {
+		return lexer.Token{}, err
+	}
+
+	tk, err := mtk.Value()
+
+	if err != nil {
+		return lexer.Token{}, ParseErrorUnexpectedEOF{
+			WhileParsing: whileParsing,
+		}
+	}
+
+	return tk, nil
+}
+
+// Consumes the next token, whatever it is, treating EOF as an error while parsing a node of the given kind.
+func (p *Parser) requireNextToken(whileParsing ASTNodeKind) (lexer.Token, error) {
+	mtk, err := p.NextToken()
+
+	return requireToken(mtk, err, whileParsing)
+}
+
+// Returns the next token without consuming it, treating EOF as an error while parsing a node of the given kind.
+func (p *Parser) requirePeekToken(whileParsing ASTNodeKind) (lexer.Token, error) {
+	mtk, err := p.PeekToken()
+
+	return requireToken(mtk, err, whileParsing)
+}
+
+// Reports whether the next token matches, without consuming it. Nothing matches at EOF.
+func (p *Parser) peekMatches(match func(lexer.Token) bool) (bool, error) {
+	mtk, err := p.PeekToken()
+
+	if err != nil {
+		return false, err
+	}
+
+	tk, err := mtk.Value()
+
+	if err != nil {
+		return false, nil
+	}
+
+	return match(tk), nil
+}
+
+// Consumes the next token only if it matches, reporting whether it did.
+func (p *Parser) acceptMatching(match func(lexer.Token) bool) (bool, error) {
+	ok, err := p.peekMatches(match)
+
+	if err != nil || !ok {
+		return false, err
+	}
+
+	_, err = p.NextToken()
+
+	return true, err
+}
+
+func isCharacter(char rune, ofKind lexer.TokenKind) func(lexer.Token) bool {
+	return func(tk lexer.Token) bool {
+		return tk.Kind() == ofKind && tk.Characters() == string(char)
+	}
+}
+
+func isOperator(operator lexer.OperatorKind) func(lexer.Token) bool {
+	return func(tk lexer.Token) bool {
+		return tk.Kind() == lexer.OperatorTokenKind && tk.Operator() == operator
+	}
+}
+
+func isKeyword(keyword lexer.KeywordKind) func(lexer.Token) bool {
+	return func(tk lexer.Token) bool {
+		return tk.Keyword() == keyword
+	}
+}
+
+func isIdentifierNamed(name string) func(lexer.Token) bool {
+	return func(tk lexer.Token) bool {
+		return tk.Kind() == lexer.IdentifierTokenKind && tk.IdentifierName() == name
+	}
+}
+
+func (p *Parser) ParseIntegerLiteral() (IntegerLiteralASTNode, error) {
+	mtk, err := p.ExpectTokenOfKind(lexer.IntegerTokenKind)
+
+	if err != nil {
+		return IntegerLiteralASTNode{}, err
+	}
+
+	tk, err := mtk.Value()
+
+	if err != nil {
+		return IntegerLiteralASTNode{}, ParseErrorUnexpectedEOF{
+			WhileParsing: IntegerLiteralASTNodeKind,
+		}
+	}
+
+	value, suffix, err := lexer.DecodeIntegerLiteral(tk.Characters())
+
+	if err != nil {
+		return IntegerLiteralASTNode{}, err
+	}
+
+	return IntegerLiteralASTNode{
+		Loc:    lexer.InitLocation(tk.Startpos(), tk.Endpos()),
+		Value:  value,
+		Suffix: suffix,
 	}, nil
 }
 
-func (p *Parser) ParseImportStatement() (ImportStatementASTNode, error) {
-	mtk, err := p.ExpectToken(lexer.InitToken(&lexer.TokenIdentifierGroup, "import", lexer.Location{}))
+func (p *Parser) ParseDecimalLiteral() (DecimalLiteralASTNode, error) {
+	mtk, err := p.ExpectTokenOfKind(lexer.DecimalTokenKind)
 
 	if err != nil {
-		return ImportStatementASTNode{}, err
+		return DecimalLiteralASTNode{}, err
 	}
 
 	tk, err := mtk.Value()
 
 	if err != nil {
-		return ImportStatementASTNode{}, ParseErrorUnexpectedEOF{
-			WhileParsing: ImportStatementASTNodeKind,
+		return DecimalLiteralASTNode{}, ParseErrorUnexpectedEOF{
+			WhileParsing: DecimalLiteralASTNodeKind,
 		}
 	}
 
+	// The token keeps its original spelling, so it is only rounded once here
+	value, err := strconv.ParseFloat(strings.ReplaceAll(tk.Characters(), "_", ""), 64)
+
+	if err != nil {
+		return DecimalLiteralASTNode{}, err
+	}
+
+	return DecimalLiteralASTNode{
+		Loc:   lexer.InitLocation(tk.Startpos(), tk.Endpos()),
+		Value: value,
+	}, nil
+}
+
+func (p *Parser) ParseCharacterLiteral() (CharacterLiteralASTNode, error) {
+	mtk, err := p.ExpectTokenOfKind(lexer.CharacterTokenKind)
+
+	if err != nil {
+		return CharacterLiteralASTNode{}, err
+	}
+
+	tk, err := mtk.Value()
+
+	if err != nil {
+		return CharacterLiteralASTNode{}, ParseErrorUnexpectedEOF{
+			WhileParsing: CharacterLiteralASTNodeKind,
+		}
+	}
+
+	value, err := lexer.DecodeCharacterLiteral(tk.Characters())
+
+	if err != nil {
+		return CharacterLiteralASTNode{}, err
+	}
+
+	return CharacterLiteralASTNode{
+		Loc:   lexer.InitLocation(tk.Startpos(), tk.Endpos()),
+		Value: value,
+	}, nil
+}
+
+func (p *Parser) ParseStringLiteral() (StringLiteralASTNode, error) {
+	mtk, err := p.ExpectTokenOfKind(lexer.StringTokenKind)
+
+	if err != nil {
+		return StringLiteralASTNode{}, err
+	}
+
+	tk, err := mtk.Value()
+
+	if err != nil {
+		return StringLiteralASTNode{}, ParseErrorUnexpectedEOF{
+			WhileParsing: StringLiteralASTNodeKind,
+		}
+	}
+
+	value, err := lexer.DecodeStringLiteral(tk.Characters())
+
+	if err != nil {
+		return StringLiteralASTNode{}, err
+	}
+
+	return StringLiteralASTNode{
+		Loc:    lexer.InitLocation(tk.Startpos(), tk.Endpos()),
+		String: value,
+	}, nil
+}
+
+// Parses an import, which is "import" or "import?" followed by an import tree and a ';', such as:
+//
+//	import std:io;
+//	import std:io as stdio;
+//	import std:data:*;
+//	import std:{io, data:{List, Map}};
+func (p *Parser) ParseImportStatement() (ImportStatementASTNode, error) {
+	mtk, err := p.ExpectKeyword(lexer.ImportKeywordKind)
+	tk, err := requireToken(mtk, err, ImportStatementASTNodeKind)
+
+	if err != nil {
+		return ImportStatementASTNode{}, err
+	}
+
 	startpos := tk.Startpos()
 
-	mtk, err = p.ExpectTokenOfGroup(&lexer.TokenIdentifierGroup)
+	optional, err := p.acceptMatching(isOperator(lexer.QuestionOperatorKind))
 
 	if err != nil {
 		return ImportStatementASTNode{}, err
 	}
 
-	tk, err = mtk.Value()
+	tree, err := p.parseImportTree()
 
 	if err != nil {
-		return ImportStatementASTNode{}, ParseErrorUnexpectedEOF{
-			WhileParsing: ImportStatementASTNodeKind,
-		}
+		return ImportStatementASTNode{}, err
 	}
 
-	path := []string{tk.Characters()}
+	mtk, err = p.ExpectCharacter(';', lexer.SeparatorTokenKind)
+
+	if _, err := requireToken(mtk, err, ImportStatementASTNodeKind); err != nil {
+		return ImportStatementASTNode{}, err
+	}
+
+	return ImportStatementASTNode{
+		Loc:        lexer.InitLocation(startpos, p.currentPos()),
+		Tree:       tree,
+		IsOptional: optional,
+	}, nil
+}
+
+// Parses a module path separated by ':', followed by an alias, a '*' or a braced group of import trees.
+func (p *Parser) parseImportTree() (ImportTree, error) {
+	tk, err := p.requirePeekToken(ImportStatementASTNodeKind)
+
+	if err != nil {
+		return ImportTree{}, err
+	}
+
+	startpos := tk.Startpos()
+	tree := ImportTree{
+		Path:     []string{},
+		Children: []ImportTree{},
+	}
 
 	for {
-		mtk, err := p.ExpectCharacter(':', &lexer.TokenSeparatorGroup)
+		tk, err := p.requireNextToken(ImportStatementASTNodeKind)
 
 		if err != nil {
-			switch err := err.(type) {
-			case ParseErrorExpectedCharacter:
-				if err.Got == ';' {
-					endpos := p.lexer.CurrentPos()
-
-					return ImportStatementASTNode{
-						Loc:  lexer.InitLocation(startpos, endpos),
-						Path: path,
-					}, nil
+			return ImportTree{}, err
+		}
+
+		switch {
+		case isOperator(lexer.AsteriskOperatorKind)(tk):
+			tree.IsWildcard = true
+		case isCharacter('{', lexer.GroupingTokenKind)(tk):
+			if tree.Children, err = p.parseImportGroup(); err != nil {
+				return ImportTree{}, err
+			}
+		case tk.Kind() == lexer.IdentifierTokenKind || tk.Kind() == lexer.QuotedIdentifierTokenKind:
+			tree.Path = append(tree.Path, tk.IdentifierName())
+
+			nested, err := p.acceptMatching(isCharacter(':', lexer.SeparatorTokenKind))
+
+			if err != nil {
+				return ImportTree{}, err
+			}
+
+			if nested {
+				continue
+			}
+
+			renamed, err := p.acceptMatching(isKeyword(lexer.AsKeywordKind))
+
+			if err != nil {
+				return ImportTree{}, err
+			}
+
+			if renamed {
+				mtk, err := p.ExpectIdentifier()
+				alias, err := requireToken(mtk, err, ImportStatementASTNodeKind)
+
+				if err != nil {
+					return ImportTree{}, err
 				}
-			default:
-				return ImportStatementASTNode{}, err
+
+				tree.Alias = alias.IdentifierName()
+			}
+		default:
+			return ImportTree{}, ParseErrorUnexpectedToken{
+				Got:          tk,
+				WhileParsing: ImportStatementASTNodeKind,
 			}
 		}
 
-		_, err = mtk.Value()
+		tree.Loc = lexer.InitLocation(startpos, p.currentPos())
+
+		return tree, nil
+	}
+}
+
+// Parses the import trees in a group after its '{', up to and including the '}'. A trailing ',' is allowed.
+// A group must import at least one tree, so "{}" is an error.
+func (p *Parser) parseImportGroup() ([]ImportTree, error) {
+	children := []ImportTree{}
+
+	for {
+		if len(children) > 0 {
+			closed, err := p.acceptMatching(isCharacter('}', lexer.GroupingTokenKind))
+
+			if err != nil {
+				return nil, err
+			}
+
+			if closed {
+				return children, nil
+			}
+		}
+
+		child, err := p.parseImportTree()
 
 		if err != nil {
-			return ImportStatementASTNode{}, ParseErrorUnexpectedEOF{
-				WhileParsing: ImportStatementASTNodeKind,
+			return nil, err
+		}
+
+		children = append(children, child)
+
+		separated, err := p.acceptMatching(isCharacter(',', lexer.SeparatorTokenKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !separated {
+			mtk, err := p.ExpectCharacter('}', lexer.GroupingTokenKind)
+
+			if _, err := requireToken(mtk, err, ImportStatementASTNodeKind); err != nil {
+				return nil, err
 			}
+
+			return children, nil
 		}
+	}
+}
+
+// Parses a block of statements in braces.
+// An expression that is not followed by a ';' must be the last thing in the block, and is the value of the block.
+func (p *Parser) ParseBlock() (BlockASTNode, error) {
+	mtk, err := p.ExpectCharacter('{', lexer.GroupingTokenKind)
+	tk, err := requireToken(mtk, err, BlockASTNodeKind)
+
+	if err != nil {
+		return BlockASTNode{}, err
+	}
 
-		mtk, err = p.ExpectTokenOfGroup(&lexer.TokenIdentifierGroup)
+	startpos := tk.Startpos()
+	code := []ASTNode{}
+
+	for {
+		closed, err := p.acceptMatching(isCharacter('}', lexer.GroupingTokenKind))
 
 		if err != nil {
-			return ImportStatementASTNode{}, err
+			return BlockASTNode{}, err
 		}
 
-		tk, err = mtk.Value()
+		if closed {
+			return BlockASTNode{
+				Loc:  lexer.InitLocation(startpos, p.currentPos()),
+				Code: code,
+			}, nil
+		}
+
+		nd, err := p.parseBlockStatement()
 
 		if err != nil {
-			return ImportStatementASTNode{}, ParseErrorUnexpectedEOF{
-				WhileParsing: ImportStatementASTNodeKind,
+			return BlockASTNode{}, err
+		}
+
+		code = append(code, nd)
+	}
+}
+
+func (p *Parser) parseBlockStatement() (Statement, error) {
+	tk, err := p.requirePeekToken(BlockASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tk.Keyword() == lexer.ReturnKeywordKind {
+		return p.parseReturnStatement()
+	}
+
+	if tk.Kind() == lexer.KeywordTokenKind {
+		mnd, err := p.ParseStatement()
+
+		if err != nil {
+			return nil, err
+		}
+
+		nd, err := mnd.Value()
+
+		if err != nil {
+			return nil, ParseErrorUnexpectedEOF{
+				WhileParsing: BlockASTNodeKind,
 			}
 		}
 
-		path = append(path, tk.Characters())
+		return nd, nil
 	}
+
+	expr, err := p.ParseExpression()
+
+	if err != nil {
+		return nil, err
+	}
+
+	terminated, err := p.acceptMatching(isCharacter(';', lexer.SeparatorTokenKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if terminated {
+		return expr, nil
+	}
+
+	tk, err = p.requirePeekToken(BlockASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !isCharacter('}', lexer.GroupingTokenKind)(tk) {
+		return nil, ParseErrorUnexpectedToken{
+			Got:          tk,
+			WhileParsing: ImplicitReturnASTNodeKind,
+		}
+	}
+
+	return ImplicitReturnASTNode{
+		Loc:   expr.Location(),
+		Value: expr,
+	}, nil
 }
 
+// Parses "return", an optional value, and the ';' after them.
+func (p *Parser) parseReturnStatement() (Statement, error) {
+	mtk, err := p.ExpectKeyword(lexer.ReturnKeywordKind)
+	tk, err := requireToken(mtk, err, ExplicitReturnASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	value := utils.NoneOptional[Expression]()
+
+	hasValue, err := p.peekMatches(startsExpression)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if hasValue {
+		expr, err := p.ParseExpression()
+
+		if err != nil {
+			return nil, err
+		}
+
+		value = utils.SomeOptional(expr)
+	}
+
+	mtk, err = p.ExpectCharacter(';', lexer.SeparatorTokenKind)
+
+	if _, err := requireToken(mtk, err, ExplicitReturnASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	return ExplicitReturnASTNode{
+		Loc:   lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		Value: value,
+	}, nil
+}
+
+// Parses a statement that begins with a keyword, or returns None at the end of the file.
+// The top level of a file is parsed like the inside of a block, so a keyword that cannot start a statement is
+// reported as an unexpected token while parsing a block.
 func (p *Parser) ParseStatement() (utils.Optional[Statement], error) {
 	mtk, err := p.PeekToken()
 
@@ -228,24 +720,51 @@ func (p *Parser) ParseStatement() (utils.Optional[Statement], error) {
 		return utils.NoneOptional[Statement](), nil
 	}
 
-	switch tk.Characters() {
-	case "import":
+	switch tk.Keyword() {
+	case lexer.ImportKeywordKind:
 		n, err := p.ParseImportStatement()
 
 		if err != nil {
-			return utils.NoneOptional[Statement](), nil
+			return utils.NoneOptional[Statement](), err
 		}
 
 		return utils.SomeOptional(Statement(n)), nil
-	case "fn":
-		n, err := p.ParseImportStatement()
+	case lexer.FnKeywordKind:
+		n, err := p.ParseFunctionDefinition()
+
+		if err != nil {
+			return utils.NoneOptional[Statement](), err
+		}
+
+		return utils.SomeOptional(Statement(n)), nil
+	case lexer.LetKeywordKind, lexer.VarKeywordKind, lexer.ConstKeywordKind:
+		n, err := p.ParseVariableDefinition()
+
+		if err != nil {
+			return utils.NoneOptional[Statement](), err
+		}
+
+		return utils.SomeOptional(Statement(n)), nil
+	case lexer.StructKeywordKind:
+		n, err := p.ParseStructureDefinition()
 
 		if err != nil {
-			return utils.NoneOptional[Statement](), nil
+			return utils.NoneOptional[Statement](), err
+		}
+
+		return utils.SomeOptional(Statement(n)), nil
+	case lexer.ClassKeywordKind:
+		n, err := p.ParseClassDefinition()
+
+		if err != nil {
+			return utils.NoneOptional[Statement](), err
 		}
 
 		return utils.SomeOptional(Statement(n)), nil
 	}
 
-	return utils.NoneOptional[Statement](), fmt.Errorf("Canot parse this node (it is either not supported or invalid) beginning with token %s", tk.ToDisplayString())
+	return utils.NoneOptional[Statement](), ParseErrorUnexpectedToken{
+		Got:          tk,
+		WhileParsing: BlockASTNodeKind,
+	}
 }
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"ljpprojects.org/sqopl/lexer"
+)
+
+func newTestParser(source string) *Parser {
+	return NewParser(lexer.NewLexerFromString("test", source))
+}
+
+func TestRewindRestoresPendingGreaterThan(t *testing.T) {
+	p := newTestParser(">>")
+
+	mark := p.Mark()
+
+	// Closing one list with ">>" leaves its second '>' pending
+	if closed, err := p.acceptClosingAngleBracket(); err != nil || !closed {
+		t.Fatalf("acceptClosingAngleBracket() = %v, %v, want true", closed, err)
+	}
+
+	if tk, err := p.pending.Value(); err != nil || tk.Operator() != lexer.GreaterThanOperatorKind {
+		t.Fatalf("no '>' is pending after closing a list with \">>\"")
+	}
+
+	p.Rewind(mark)
+
+	if _, err := p.pending.Value(); err == nil {
+		t.Errorf("a '>' is still pending after rewinding to before the \">>\"")
+	}
+
+	closedTwice, err := p.peekMatches(isOperator(lexer.ShiftRightOperatorKind))
+
+	if err != nil || !closedTwice {
+		t.Errorf("the next token is not \">>\" after rewinding")
+	}
+}
+
+// Writes out an import tree in the same form as the source, such as std:{io, data:* as d}, so that tests can compare it.
+func describeImportTree(tree ImportTree) string {
+	parts := slices.Clone(tree.Path)
+
+	if tree.IsWildcard {
+		parts = append(parts, "*")
+	}
+
+	if len(tree.Children) > 0 {
+		children := []string{}
+
+		for _, child := range tree.Children {
+			children = append(children, describeImportTree(child))
+		}
+
+		parts = append(parts, "{"+strings.Join(children, ", ")+"}")
+	}
+
+	described := strings.Join(parts, ":")
+
+	if tree.Alias != "" {
+		described += " as " + tree.Alias
+	}
+
+	return described
+}
+
+func TestParseImportStatement(t *testing.T) {
+	tests := []struct {
+		source   string
+		want     string
+		optional bool
+	}{
+		{"import io;", "io", false},
+		{"import std:io;", "std:io", false},
+		{"import std:io as stdio;", "std:io as stdio", false},
+		{"import std:data:*;", "std:data:*", false},
+		{"import std:{io, exitcode};", "std:{io, exitcode}", false},
+		{"import std:{io, data:{List, Map as Dictionary},};", "std:{io, data:{List, Map as Dictionary}}", false},
+		{"import std:{io as stdio, data:*};", "std:{io as stdio, data:*}", false},
+		{"import {io, exitcode};", "{io, exitcode}", false},
+		{"import? std:io;", "std:io", true},
+		{"import? std:{io, data:*};", "std:{io, data:*}", true},
+	}
+
+	for _, test := range tests {
+		statement, err := newTestParser(test.source).ParseImportStatement()
+
+		if err != nil {
+			t.Errorf("parsing %q: %v", test.source, err)
+			continue
+		}
+
+		if got := describeImportTree(statement.Tree); got != test.want {
+			t.Errorf("parsing %q: got %s, want %s", test.source, got, test.want)
+		}
+
+		if statement.IsOptional != test.optional {
+			t.Errorf("parsing %q: got IsOptional %t, want %t", test.source, statement.IsOptional, test.optional)
+		}
+	}
+}
+
+func TestParseInvalidImportStatement(t *testing.T) {
+	tests := []struct {
+		source string
+		got    string
+	}{
+		{"import std:{};", "}"},
+		{"import std:{io, data:{}};", "}"},
+		{"import std:{io,,};", ","},
+		{"import std:;", ";"},
+	}
+
+	for _, test := range tests {
+		_, err := newTestParser(test.source).ParseImportStatement()
+
+		var unexpected ParseErrorUnexpectedToken
+
+		if !errors.As(err, &unexpected) {
+			t.Errorf("parsing %q: got error %v, want a ParseErrorUnexpectedToken", test.source, err)
+			continue
+		}
+
+		if unexpected.Got.Characters() != test.got {
+			t.Errorf("parsing %q: got unexpected token %s, want %q", test.source, unexpected.Got.ToDisplayString(), test.got)
+		}
+	}
+}
@@ -0,0 +1,609 @@
+package parser
+
+import (
+	"fmt"
+
+	"ljpprojects.org/sqopl/lexer"
+	"ljpprojects.org/sqopl/utils"
+)
+
+// Parses a type. From loosest to tightest binding, types are:
+//
+//	Type "|" Type                                   an untagged union of two or more types
+//	Type "?"                                        a nullable type
+//	["escaping"] ("mut" | "const") "&" ["?"] Type   a reference, which is nullable if followed by '?'
+//	["escaping"] ["mut" | "const"] "[" "]" Type     a slice
+//	"[" Length "]" Type                             an array
+//	"table" "[" Type "]" Type                       a table from keys of the first type to values of the second
+//	"*" Type                                        a raw pointer
+//	"(" Type {"," Type} [","] ")"                   a tuple, or just the type in parentheses if it has one type and no ','
+//	"!"                                             the never type, of expressions that never produce a value
+//	{Module ":"} Name ["<" Type {"," Type} ">"]     a named type
+//
+// The type after a prefix such as mut&, [] or * may be nullable, so mut& T? is a reference to a nullable T,
+// while (mut& T)? and mut&? T are nullable references.
+//
+// This parses a type that is not inside another, such as the type of a parameter, so a '>' left over from
+// a ">>" that closed the type's generics is reported as unexpected instead of being left for what comes next.
+func (p *Parser) ParseType() (Type, error) {
+	typ, err := p.parseType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tk, err := p.pending.Value(); err == nil && tk.Operator() == lexer.GreaterThanOperatorKind {
+		p.pending = utils.NoneOptional[lexer.Token]()
+
+		return nil, ParseErrorUnexpectedToken{
+			Got:          tk,
+			WhileParsing: NamedTypeASTNodeKind,
+		}
+	}
+
+	return typ, nil
+}
+
+// Parses a type that may be inside another, such as a generic argument. See Parser.ParseType.
+func (p *Parser) parseType() (Type, error) {
+	first, err := p.parseNullableType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	types := []Type{first}
+
+	for {
+		union, err := p.acceptMatching(isOperator(lexer.PipeOperatorKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !union {
+			break
+		}
+
+		typ, err := p.parseNullableType()
+
+		if err != nil {
+			return nil, err
+		}
+
+		types = append(types, typ)
+	}
+
+	if len(types) == 1 {
+		return first, nil
+	}
+
+	return UntaggedUnionTypeASTNode{
+		Loc:   lexer.InitLocation(first.Location().Start, p.currentPos()),
+		Types: types,
+	}, nil
+}
+
+func (p *Parser) parseNullableType() (Type, error) {
+	typ, err := p.parsePrefixedType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		nullable, err := p.acceptMatching(isOperator(lexer.QuestionOperatorKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !nullable {
+			break
+		}
+
+		typ = NullableTypeASTNode{
+			Loc:   lexer.InitLocation(typ.Location().Start, p.currentPos()),
+			Inner: typ,
+		}
+	}
+
+	return typ, nil
+}
+
+func (p *Parser) parsePrefixedType() (Type, error) {
+	tk, err := p.requirePeekToken(NamedTypeASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	startpos := tk.Startpos()
+
+	switch {
+	case isOperator(lexer.AsteriskOperatorKind)(tk):
+		if _, err := p.NextToken(); err != nil {
+			return nil, err
+		}
+
+		inner, err := p.parseNullableType()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return RawPointer{
+			Loc:   lexer.InitLocation(startpos, p.currentPos()),
+			Inner: inner,
+		}, nil
+	case isOperator(lexer.BangOperatorKind)(tk):
+		if _, err := p.NextToken(); err != nil {
+			return nil, err
+		}
+
+		return NeverTypeASTNode{
+			Loc: lexer.InitLocation(tk.Startpos(), tk.Endpos()),
+		}, nil
+	case isKeyword(lexer.TableKeywordKind)(tk):
+		return p.parseTableType()
+	case isCharacter('(', lexer.GroupingTokenKind)(tk):
+		return p.parseTupleType()
+	}
+
+	escaping, err := p.acceptMatching(isKeyword(lexer.EscapingKeywordKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	mutable, err := p.acceptMatching(isKeyword(lexer.MutKeywordKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	constant := false
+
+	if !mutable {
+		if constant, err = p.acceptMatching(isKeyword(lexer.ConstKeywordKind)); err != nil {
+			return nil, err
+		}
+	}
+
+	isBracketed, err := p.acceptMatching(isCharacter('[', lexer.GroupingTokenKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isBracketed {
+		isArray, err := p.peekMatches(func(tk lexer.Token) bool {
+			return tk.Kind() == lexer.IntegerTokenKind
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		// References and escaping only apply to slices, since arrays are values
+		if isArray && !escaping && !mutable && !constant {
+			return p.parseArrayType(startpos)
+		}
+
+		return p.parseSliceType(startpos, mutable, escaping)
+	}
+
+	if mutable || constant {
+		return p.parseReferenceType(startpos, mutable, escaping)
+	}
+
+	if escaping {
+		tk, err := p.requireNextToken(MutableReferenceTypeASTNodeKind)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ParseErrorUnexpectedToken{
+			Got:          tk,
+			WhileParsing: MutableReferenceTypeASTNodeKind,
+		}
+	}
+
+	return p.ParseNamedType()
+}
+
+// Parses the rest of a slice type after its '['.
+func (p *Parser) parseSliceType(startpos lexer.Position, mutable bool, escaping bool) (Type, error) {
+	mtk, err := p.ExpectCharacter(']', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, SliceTypeASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	valueType, err := p.parseNullableType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return SliceTypeASTNode{
+		Loc:        lexer.InitLocation(startpos, p.currentPos()),
+		ValueType:  valueType,
+		IsMutable:  mutable,
+		IsEscaping: escaping,
+	}, nil
+}
+
+// Parses the rest of an array type after its '[', such as 4]Integer32.
+func (p *Parser) parseArrayType(startpos lexer.Position) (Type, error) {
+	mtk, err := p.ExpectTokenOfKind(lexer.IntegerTokenKind)
+	tk, err := requireToken(mtk, err, ArrayTypeASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	length, _, err := lexer.DecodeIntegerLiteral(tk.Characters())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !length.IsUint64() {
+		return nil, fmt.Errorf("Array length in token %s does not fit in 64 bits", tk.ToDisplayString())
+	}
+
+	mtk, err = p.ExpectCharacter(']', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, ArrayTypeASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	valueType, err := p.parseNullableType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ArrayTypeASTNode{
+		Loc:       lexer.InitLocation(startpos, p.currentPos()),
+		ValueType: valueType,
+		Length:    length.Uint64(),
+	}, nil
+}
+
+// Parses a table type, such as table[String]Integer32.
+func (p *Parser) parseTableType() (Type, error) {
+	mtk, err := p.ExpectKeyword(lexer.TableKeywordKind)
+	tk, err := requireToken(mtk, err, TableTypeASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mtk, err = p.ExpectCharacter('[', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, TableTypeASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	keyType, err := p.parseType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	mtk, err = p.ExpectCharacter(']', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, TableTypeASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	valueType, err := p.parseNullableType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return TableTypeASTNode{
+		Loc:       lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		KeyType:   keyType,
+		ValueType: valueType,
+	}, nil
+}
+
+// Parses a tuple type, or a single type in parentheses, which is returned as it is.
+func (p *Parser) parseTupleType() (Type, error) {
+	mtk, err := p.ExpectCharacter('(', lexer.GroupingTokenKind)
+	tk, err := requireToken(mtk, err, TupleTypeASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	valueTypes := []Type{}
+	separated := false
+
+	for {
+		closed, err := p.acceptMatching(isCharacter(')', lexer.GroupingTokenKind))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if closed {
+			break
+		}
+
+		typ, err := p.parseType()
+
+		if err != nil {
+			return nil, err
+		}
+
+		valueTypes = append(valueTypes, typ)
+
+		if separated, err = p.acceptMatching(isCharacter(',', lexer.SeparatorTokenKind)); err != nil {
+			return nil, err
+		}
+
+		if !separated {
+			mtk, err := p.ExpectCharacter(')', lexer.GroupingTokenKind)
+
+			if _, err := requireToken(mtk, err, TupleTypeASTNodeKind); err != nil {
+				return nil, err
+			}
+
+			break
+		}
+	}
+
+	if len(valueTypes) == 1 && !separated {
+		return valueTypes[0], nil
+	}
+
+	return TupleTypeASTNode{
+		Loc:        lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		ValueTypes: valueTypes,
+	}, nil
+}
+
+// Parses the rest of a reference type after its "mut" or "const" keyword.
+func (p *Parser) parseReferenceType(startpos lexer.Position, mutable bool, escaping bool) (Type, error) {
+	kind := ImmutableReferenceTypeASTNodeKind
+
+	if mutable {
+		kind = MutableReferenceTypeASTNodeKind
+	}
+
+	mtk, err := p.ExpectOperator(lexer.AmpersandOperatorKind)
+
+	if _, err := requireToken(mtk, err, kind); err != nil {
+		return nil, err
+	}
+
+	nullable, err := p.acceptMatching(isOperator(lexer.QuestionOperatorKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := p.parseNullableType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	loc := lexer.InitLocation(startpos, p.currentPos())
+
+	var ref Type = ImmutableReference{
+		Loc:        loc,
+		IsEscaping: escaping,
+		Inner:      inner,
+	}
+
+	if mutable {
+		ref = MutableReference{
+			Loc:        loc,
+			IsEscaping: escaping,
+			Inner:      inner,
+		}
+	}
+
+	if nullable {
+		return NullableTypeASTNode{
+			Loc:   loc,
+			Inner: ref,
+		}, nil
+	}
+
+	return ref, nil
+}
+
+// Parses the type after "is", "as" or "as?", which binds more tightly than ParseType, as it is in an expression.
+//
+// Unions must be parenthesised, so the '|' in a is T | b is a bitwise or.
+// A '<' after a named type only starts its generic arguments if they are closed by a '>',
+// so a as Integer64 < b is a comparison, while a as List<T> < b casts to List<T>.
+func (p *Parser) parseCastType() (Type, error) {
+	tk, err := p.requirePeekToken(NamedTypeASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tk.Kind() != lexer.IdentifierTokenKind && tk.Kind() != lexer.QuotedIdentifierTokenKind {
+		return p.parseNullableType()
+	}
+
+	mark := p.Mark()
+
+	typ, err := p.parseNullableType()
+
+	if err == nil {
+		p.Commit(mark)
+
+		return typ, nil
+	}
+
+	// Errors from the lexer would not be found again after rewinding, so they are never backed off from
+	if _, ok := err.(lexer.LexError); ok {
+		p.Commit(mark)
+
+		return nil, err
+	}
+
+	p.Rewind(mark)
+
+	startpos, path, err := p.parseTypePath()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NamedTypeASTNode{
+		Loc:              lexer.InitLocation(startpos, p.currentPos()),
+		Module:           path[:len(path)-1],
+		Name:             path[len(path)-1],
+		GenericArguments: []Type{},
+	}, nil
+}
+
+// Parses a named type, such as Integer32, core:CInt or List<T>.
+func (p *Parser) ParseNamedType() (NamedTypeASTNode, error) {
+	startpos, path, err := p.parseTypePath()
+
+	if err != nil {
+		return NamedTypeASTNode{}, err
+	}
+
+	arguments := []Type{}
+
+	hasArguments, err := p.acceptMatching(isOperator(lexer.LessThanOperatorKind))
+
+	if err != nil {
+		return NamedTypeASTNode{}, err
+	}
+
+	if hasArguments {
+		err := p.parseAngleBracketList(NamedTypeASTNodeKind, func() error {
+			argument, err := p.parseType()
+
+			if err != nil {
+				return err
+			}
+
+			arguments = append(arguments, argument)
+
+			return nil
+		})
+
+		if err != nil {
+			return NamedTypeASTNode{}, err
+		}
+	}
+
+	return NamedTypeASTNode{
+		Loc:              lexer.InitLocation(startpos, p.currentPos()),
+		Module:           path[:len(path)-1],
+		Name:             path[len(path)-1],
+		GenericArguments: arguments,
+	}, nil
+}
+
+// Parses the module path and name of a named type, such as core:CInt, returning where it starts and each part of it in order.
+func (p *Parser) parseTypePath() (lexer.Position, []string, error) {
+	mtk, err := p.ExpectIdentifier()
+	tk, err := requireToken(mtk, err, NamedTypeASTNodeKind)
+
+	if err != nil {
+		return lexer.Position{}, nil, err
+	}
+
+	path := []string{tk.IdentifierName()}
+
+	for {
+		qualified, err := p.acceptMatching(isCharacter(':', lexer.SeparatorTokenKind))
+
+		if err != nil {
+			return lexer.Position{}, nil, err
+		}
+
+		if !qualified {
+			return tk.Startpos(), path, nil
+		}
+
+		mtk, err := p.ExpectIdentifier()
+		nameTk, err := requireToken(mtk, err, NamedTypeASTNodeKind)
+
+		if err != nil {
+			return lexer.Position{}, nil, err
+		}
+
+		path = append(path, nameTk.IdentifierName())
+	}
+}
+
+// Parses a list of generics after its '<', calling item to parse each one, up to and including the '>'.
+// A trailing ',' is allowed.
+func (p *Parser) parseAngleBracketList(whileParsing ASTNodeKind, item func() error) error {
+	for {
+		closed, err := p.acceptClosingAngleBracket()
+
+		if err != nil || closed {
+			return err
+		}
+
+		if err := item(); err != nil {
+			return err
+		}
+
+		closed, err = p.acceptClosingAngleBracket()
+
+		if err != nil || closed {
+			return err
+		}
+
+		mtk, err := p.ExpectCharacter(',', lexer.SeparatorTokenKind)
+
+		if _, err := requireToken(mtk, err, whileParsing); err != nil {
+			return err
+		}
+	}
+}
+
+// Consumes the '>' that closes a list of generics, if it comes next.
+// The lexer reads operators such as ">>", ">=" and ">>=" as one token, so when one of them closes a list,
+// it is split after its first '>', and the rest of it is left in Parser.pending for what comes next.
+func (p *Parser) acceptClosingAngleBracket() (bool, error) {
+	mtk, err := p.PeekToken()
+
+	if err != nil {
+		return false, err
+	}
+
+	tk, err := mtk.Value()
+
+	if err != nil || tk.Kind() != lexer.OperatorTokenKind {
+		return false, nil
+	}
+
+	switch tk.Operator() {
+	case lexer.GreaterThanOperatorKind:
+		_, err := p.NextToken()
+
+		return err == nil, err
+	case lexer.ShiftRightOperatorKind, lexer.GreaterThanEqualsOperatorKind, lexer.ShiftRightEqualsOperatorKind:
+		if _, err := p.NextToken(); err != nil {
+			return false, err
+		}
+
+		_, rest := tk.SplitOperator(1)
+		p.pending = utils.SomeOptional(rest)
+
+		return true, nil
+	}
+
+	return false, nil
+}
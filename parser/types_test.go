@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ljpprojects.org/sqopl/lexer"
+	"ljpprojects.org/sqopl/utils"
+)
+
+// Writes out a type in the syntax it is parsed from, with unions and nullable references in parentheses.
+func describeType(t Type) string {
+	switch t := t.(type) {
+	case NamedTypeASTNode:
+		name := strings.Join(append(append([]string{}, t.Module...), t.Name), ":")
+
+		if len(t.GenericArguments) == 0 {
+			return name
+		}
+
+		return fmt.Sprintf("%s<%s>", name, describeTypes(t.GenericArguments, ", "))
+	case NullableTypeASTNode:
+		if _, ok := t.Inner.(RefType); ok {
+			return "(" + describeType(t.Inner) + ")?"
+		}
+
+		return describeType(t.Inner) + "?"
+	case UntaggedUnionTypeASTNode:
+		return "(" + describeTypes(t.Types, " | ") + ")"
+	case TupleTypeASTNode:
+		if len(t.ValueTypes) == 1 {
+			return "(" + describeType(t.ValueTypes[0]) + ",)"
+		}
+
+		return "(" + describeTypes(t.ValueTypes, ", ") + ")"
+	case TableTypeASTNode:
+		return fmt.Sprintf("table[%s]%s", describeType(t.KeyType), describeType(t.ValueType))
+	case ArrayTypeASTNode:
+		return fmt.Sprintf("[%d]%s", t.Length, describeType(t.ValueType))
+	case SliceTypeASTNode:
+		return describeModifiers(t.IsEscaping, t.IsMutable, false) + "[]" + describeType(t.ValueType)
+	case MutableReference:
+		return describeModifiers(t.IsEscaping, true, false) + "&" + describeType(t.Inner)
+	case ImmutableReference:
+		return describeModifiers(t.IsEscaping, false, true) + "&" + describeType(t.Inner)
+	case RawPointer:
+		return "*" + describeType(t.Inner)
+	case NeverTypeASTNode:
+		return "!"
+	}
+
+	return fmt.Sprintf("<%T>", t)
+}
+
+func describeTypes(types []Type, separator string) string {
+	described := []string{}
+
+	for _, t := range types {
+		described = append(described, describeType(t))
+	}
+
+	return strings.Join(described, separator)
+}
+
+func describeModifiers(escaping bool, mutable bool, constant bool) string {
+	modifiers := ""
+
+	if escaping {
+		modifiers += "escaping "
+	}
+
+	if mutable {
+		modifiers += "mut"
+	}
+
+	if constant {
+		modifiers += "const"
+	}
+
+	return modifiers
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"Integer32", "Integer32"},
+		{"core:CInt", "core:CInt"},
+		{"List<T>", "List<T>"},
+		{"List<List<T>>", "List<List<T>>"},
+		{"Map<K, List<List<V>>>", "Map<K, List<List<V>>>"},
+		{"Map<K, V,>", "Map<K, V>"},
+		{"table[String]Integer32", "table[String]Integer32"},
+		{"table[String]List<T>?", "table[String]List<T>?"},
+		{"[4]Integer32", "[4]Integer32"},
+		{"[0x10][2]Byte", "[16][2]Byte"},
+		{"[]Integer32", "[]Integer32"},
+		{"mut []Integer32", "mut[]Integer32"},
+		{"escaping const []Byte", "escaping []Byte"},
+		{"(Integer32, String)", "(Integer32, String)"},
+		{"(Integer32,)", "(Integer32,)"},
+		{"(Integer32)", "Integer32"},
+		{"()", "()"},
+		{"*Byte", "*Byte"},
+		{"**Byte?", "**Byte?"},
+		{"!", "!"},
+		{"A | B? | !", "(A | B? | !)"},
+		{"(A | B)?", "(A | B)?"},
+		{"mut& Data", "mut&Data"},
+		{"escaping const& Data?", "escaping const&Data?"},
+		{"mut&? Data", "(mut&Data)?"},
+	}
+
+	for _, test := range tests {
+		typ, err := newTestParser(test.source).ParseType()
+
+		if err != nil {
+			t.Errorf("parsing %q: %v", test.source, err)
+			continue
+		}
+
+		if got := describeType(typ); got != test.want {
+			t.Errorf("parsing %q: got %s, want %s", test.source, got, test.want)
+		}
+	}
+}
+
+func TestClosingGenericsWithCompoundOperators(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"var x List<T>= v;", "List<T>"},
+		{"var x List<List<T>>= v;", "List<List<T>>"},
+		{"let x Map<K, List<V>>= v;", "Map<K, List<V>>"},
+	}
+
+	for _, test := range tests {
+		def, err := newTestParser(test.source).ParseVariableDefinition()
+
+		if err != nil {
+			t.Errorf("parsing %q: %v", test.source, err)
+			continue
+		}
+
+		var typ utils.Optional[Type]
+
+		switch def := def.(type) {
+		case VarDefinitionASTNode:
+			typ = def.Type
+		case LetDefinitionASTNode:
+			typ = def.Type
+		}
+
+		if got, err := typ.Value(); err != nil || describeType(got) != test.want {
+			t.Errorf("parsing %q: got type %v, want %s", test.source, got, test.want)
+		}
+	}
+}
+
+func TestUnexpectedClosingAngleBracket(t *testing.T) {
+	tests := []struct {
+		name   string
+		parse  func(p *Parser) error
+		source string
+		column uint32
+	}{
+		{"type", func(p *Parser) error { _, err := p.ParseType(); return err }, "List<T>>", 8},
+		{"parameter", func(p *Parser) error { _, err := p.ParseFunctionDefinition(); return err }, "fn f(a List<T>>, b List<U>);", 15},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestParser(test.source)
+			err := test.parse(p)
+
+			var unexpected ParseErrorUnexpectedToken
+
+			if !errors.As(err, &unexpected) {
+				t.Fatalf("parsing %q: got error %v, want a ParseErrorUnexpectedToken", test.source, err)
+			}
+
+			if unexpected.Got.Operator() != lexer.GreaterThanOperatorKind || unexpected.Got.Startpos().Column() != test.column {
+				t.Errorf("parsing %q: got unexpected token %s, want the '>' at column %d", test.source, unexpected.Got.ToDisplayString(), test.column)
+			}
+
+			if _, err := p.pending.Value(); err == nil {
+				t.Errorf("parsing %q: the '>' is still pending after it was reported", test.source)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package parser
+
+import (
+	"ljpprojects.org/sqopl/lexer"
+	"ljpprojects.org/sqopl/utils"
+)
+
+// Parses a let, var or const definition, which is one of:
+//
+//	("let" | "var" | "const") Name [Type] "=" Expression ";"
+//	("let" | "var") Name Type ";"                              assigned later
+//	"var" Name Type "{" Getter [Setter] "}"                    a computed var
+//
+// The type may be left out when it can be inferred from the value, as in let z = 3;.
+func (p *Parser) ParseVariableDefinition() (Definition, error) {
+	tk, err := p.requireNextToken(LetDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	startpos := tk.Startpos()
+	keyword := tk.Keyword()
+
+	var kind ASTNodeKind
+
+	switch keyword {
+	case lexer.LetKeywordKind:
+		kind = LetDefinitionASTNodeKind
+	case lexer.VarKeywordKind:
+		kind = VarDefinitionASTNodeKind
+	case lexer.ConstKeywordKind:
+		kind = ConstDefinitionASTNodeKind
+	default:
+		return nil, ParseErrorUnexpectedToken{
+			Got:          tk,
+			WhileParsing: LetDefinitionASTNodeKind,
+		}
+	}
+
+	mtk, err := p.ExpectIdentifier()
+	nameTk, err := requireToken(mtk, err, kind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	typ := utils.NoneOptional[Type]()
+
+	inferred, err := p.peekMatches(isOperator(lexer.EqualsOperatorKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !inferred {
+		t, err := p.ParseType()
+
+		if err != nil {
+			return nil, err
+		}
+
+		typ = utils.SomeOptional(t)
+
+		if keyword == lexer.VarKeywordKind {
+			computed, err := p.peekMatches(isCharacter('{', lexer.GroupingTokenKind))
+
+			if err != nil {
+				return nil, err
+			}
+
+			if computed {
+				return p.parseComputedVarBody(startpos, nameTk.IdentifierName(), t)
+			}
+		}
+
+		if keyword != lexer.ConstKeywordKind {
+			uninitialised, err := p.acceptMatching(isCharacter(';', lexer.SeparatorTokenKind))
+
+			if err != nil {
+				return nil, err
+			}
+
+			if uninitialised {
+				loc := lexer.InitLocation(startpos, p.currentPos())
+
+				if keyword == lexer.LetKeywordKind {
+					return LetDefinitionASTNode{Loc: loc, Name: nameTk.IdentifierName(), Value: utils.NoneOptional[Expression](), Type: typ}, nil
+				}
+
+				return VarDefinitionASTNode{Loc: loc, Name: nameTk.IdentifierName(), Value: utils.NoneOptional[Expression](), Type: typ}, nil
+			}
+		}
+	}
+
+	mtk, err = p.ExpectOperator(lexer.EqualsOperatorKind)
+
+	if _, err := requireToken(mtk, err, kind); err != nil {
+		return nil, err
+	}
+
+	value, err := p.ParseExpression()
+
+	if err != nil {
+		return nil, err
+	}
+
+	mtk, err = p.ExpectCharacter(';', lexer.SeparatorTokenKind)
+
+	if _, err := requireToken(mtk, err, kind); err != nil {
+		return nil, err
+	}
+
+	loc := lexer.InitLocation(startpos, p.currentPos())
+
+	switch keyword {
+	case lexer.LetKeywordKind:
+		return LetDefinitionASTNode{Loc: loc, Name: nameTk.IdentifierName(), Value: utils.SomeOptional(value), Type: typ}, nil
+	case lexer.VarKeywordKind:
+		return VarDefinitionASTNode{Loc: loc, Name: nameTk.IdentifierName(), Value: utils.SomeOptional(value), Type: typ}, nil
+	}
+
+	return ConstDefinitionASTNode{Loc: loc, Name: nameTk.IdentifierName(), Value: value, Type: typ}, nil
+}
+
+// Parses the braced getter and optional setter of a computed var, such as:
+//
+//	var Area Integer32 {
+//	    get(const& self) { self.width * self.height }
+//	    set(mut& self, area) { self.width = area / self.height; }
+//	}
+//
+// The self parameter is left out for computed vars that are not in a type.
+func (p *Parser) parseComputedVarBody(startpos lexer.Position, name string, valueType Type) (Definition, error) {
+	mtk, err := p.ExpectCharacter('{', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, ComputedVarDefinitionASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	getter, err := p.parseGetter()
+
+	if err != nil {
+		return nil, err
+	}
+
+	setter := utils.NoneOptional[SetterMethodDef]()
+
+	hasSetter, err := p.peekMatches(isIdentifierNamed("set"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if hasSetter {
+		s, err := p.parseSetter()
+
+		if err != nil {
+			return nil, err
+		}
+
+		setter = utils.SomeOptional(s)
+	}
+
+	tk, err := p.requirePeekToken(ComputedVarDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isIdentifierNamed("get")(tk) || isIdentifierNamed("set")(tk) {
+		return nil, ParseErrorDuplicateName{
+			Name:         tk,
+			WhileParsing: ComputedVarDefinitionASTNodeKind,
+		}
+	}
+
+	mtk, err = p.ExpectCharacter('}', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, ComputedVarDefinitionASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	return ComputedVarDefinitionASTNode{
+		Loc:       lexer.InitLocation(startpos, p.currentPos()),
+		Name:      name,
+		Getter:    getter,
+		Setter:    setter,
+		ValueType: valueType,
+	}, nil
+}
+
+// Parses "get", an optional parenthesised self parameter, and the getter's body.
+func (p *Parser) parseGetter() (GetterMethodDef, error) {
+	tk, err := p.expectIdentifierNamed("get")
+
+	if err != nil {
+		return GetterMethodDef{}, err
+	}
+
+	var selfType RefType
+
+	hasParameters, err := p.acceptMatching(isCharacter('(', lexer.GroupingTokenKind))
+
+	if err != nil {
+		return GetterMethodDef{}, err
+	}
+
+	if hasParameters {
+		if selfType, err = p.parseSelfParameter(); err != nil {
+			return GetterMethodDef{}, err
+		}
+
+		mtk, err := p.ExpectCharacter(')', lexer.GroupingTokenKind)
+
+		if _, err := requireToken(mtk, err, ComputedVarDefinitionASTNodeKind); err != nil {
+			return GetterMethodDef{}, err
+		}
+	}
+
+	body, err := p.ParseBlock()
+
+	if err != nil {
+		return GetterMethodDef{}, err
+	}
+
+	return GetterMethodDef{
+		Loc:      lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		Body:     body,
+		SelfType: selfType,
+	}, nil
+}
+
+// Parses "set", its parameters, which are an optional self parameter and the name of the new value, and the setter's body.
+func (p *Parser) parseSetter() (SetterMethodDef, error) {
+	tk, err := p.expectIdentifierNamed("set")
+
+	if err != nil {
+		return SetterMethodDef{}, err
+	}
+
+	mtk, err := p.ExpectCharacter('(', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, ComputedVarDefinitionASTNodeKind); err != nil {
+		return SetterMethodDef{}, err
+	}
+
+	var selfType RefType
+
+	hasSelf, err := p.peekMatches(startsSelfParameter)
+
+	if err != nil {
+		return SetterMethodDef{}, err
+	}
+
+	if hasSelf {
+		if selfType, err = p.parseSelfParameter(); err != nil {
+			return SetterMethodDef{}, err
+		}
+
+		mtk, err := p.ExpectCharacter(',', lexer.SeparatorTokenKind)
+
+		if _, err := requireToken(mtk, err, ComputedVarDefinitionASTNodeKind); err != nil {
+			return SetterMethodDef{}, err
+		}
+	}
+
+	mtk, err = p.ExpectIdentifier()
+	valueTk, err := requireToken(mtk, err, ComputedVarDefinitionASTNodeKind)
+
+	if err != nil {
+		return SetterMethodDef{}, err
+	}
+
+	mtk, err = p.ExpectCharacter(')', lexer.GroupingTokenKind)
+
+	if _, err := requireToken(mtk, err, ComputedVarDefinitionASTNodeKind); err != nil {
+		return SetterMethodDef{}, err
+	}
+
+	body, err := p.ParseBlock()
+
+	if err != nil {
+		return SetterMethodDef{}, err
+	}
+
+	return SetterMethodDef{
+		Loc:             lexer.InitLocation(tk.Startpos(), p.currentPos()),
+		Body:            body,
+		SelfType:        selfType,
+		NewValueArgName: valueTk.IdentifierName(),
+	}, nil
+}
+
+// Parses a self parameter, such as const& self or escaping mut& self, returning the reference type of self.
+// The inner type of the reference is nil, since it is the type that the parameter is in.
+func (p *Parser) parseSelfParameter() (RefType, error) {
+	tk, err := p.requirePeekToken(ComputedVarDefinitionASTNodeKind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	startpos := tk.Startpos()
+
+	escaping, err := p.acceptMatching(isKeyword(lexer.EscapingKeywordKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	mutable, err := p.acceptMatching(isKeyword(lexer.MutKeywordKind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !mutable {
+		mtk, err := p.ExpectKeyword(lexer.ConstKeywordKind)
+
+		if _, err := requireToken(mtk, err, ImmutableReferenceTypeASTNodeKind); err != nil {
+			return nil, err
+		}
+	}
+
+	mtk, err := p.ExpectOperator(lexer.AmpersandOperatorKind)
+
+	if _, err := requireToken(mtk, err, ImmutableReferenceTypeASTNodeKind); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expectIdentifierNamed("self"); err != nil {
+		return nil, err
+	}
+
+	loc := lexer.InitLocation(startpos, p.currentPos())
+
+	if mutable {
+		return MutableReference{Loc: loc, IsEscaping: escaping}, nil
+	}
+
+	return ImmutableReference{Loc: loc, IsEscaping: escaping}, nil
+}
+
+// Reports whether a self parameter can begin with tk. Other parameters begin with their name.
+func startsSelfParameter(tk lexer.Token) bool {
+	switch tk.Keyword() {
+	case lexer.EscapingKeywordKind, lexer.MutKeywordKind, lexer.ConstKeywordKind:
+		return true
+	}
+
+	return false
+}
+
+// Expects an identifier with the given name, for words such as get, set and self that are not keywords.
+func (p *Parser) expectIdentifierNamed(name string) (lexer.Token, error) {
+	tk, err := p.requireNextToken(ComputedVarDefinitionASTNodeKind)
+
+	if err != nil {
+		return lexer.Token{}, err
+	}
+
+	if !isIdentifierNamed(name)(tk) {
+		return lexer.Token{}, ParseErrorUnexpectedToken{
+			Got:          tk,
+			WhileParsing: ComputedVarDefinitionASTNodeKind,
+		}
+	}
+
+	return tk, nil
+}
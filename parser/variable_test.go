@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"ljpprojects.org/sqopl/utils"
+)
+
+func TestParseVariableDefinition(t *testing.T) {
+	tests := []struct {
+		source string
+		kind   ASTNodeKind
+		name   string
+		typ    string
+		value  string
+	}{
+		{"let z = 3;", LetDefinitionASTNodeKind, "z", "", "3"},
+		{"var x Integer32 = 9;", VarDefinitionASTNodeKind, "x", "Integer32", "9"},
+		{"const y = 34;", ConstDefinitionASTNodeKind, "y", "", "34"},
+		{"const Limit Integer64 = 1 << 20;", ConstDefinitionASTNodeKind, "Limit", "Integer64", "(1 << 20)"},
+		{"let data escaping mut& Data = other;", LetDefinitionASTNodeKind, "data", "escaping mut&Data", "other"},
+		{"let instance const& Data = Data(number: 0);", LetDefinitionASTNodeKind, "instance", "const&Data", "Data(number: 0)"},
+		{"let x Integer32;", LetDefinitionASTNodeKind, "x", "Integer32", ""},
+		{"var names List<String>;", VarDefinitionASTNodeKind, "names", "List<String>", ""},
+	}
+
+	for _, test := range tests {
+		def, err := newTestParser(test.source).ParseVariableDefinition()
+
+		if err != nil {
+			t.Errorf("parsing %q: %v", test.source, err)
+			continue
+		}
+
+		var (
+			name  string
+			typ   utils.Optional[Type]
+			value utils.Optional[Expression]
+		)
+
+		switch def := def.(type) {
+		case LetDefinitionASTNode:
+			name, typ, value = def.Name, def.Type, def.Value
+		case VarDefinitionASTNode:
+			name, typ, value = def.Name, def.Type, def.Value
+		case ConstDefinitionASTNode:
+			name, typ, value = def.Name, def.Type, utils.SomeOptional(def.Value)
+		}
+
+		if def.Kind() != test.kind || name != test.name {
+			t.Errorf("parsing %q: got %s %s, want %s %s", test.source, def.Kind().ToDisplayString(), name, test.kind.ToDisplayString(), test.name)
+		}
+
+		gotType := ""
+
+		if got, err := typ.Value(); err == nil {
+			gotType = describeType(got)
+		}
+
+		if gotType != test.typ {
+			t.Errorf("parsing %q: got type %q, want %q", test.source, gotType, test.typ)
+		}
+
+		gotValue := ""
+
+		if got, err := value.Value(); err == nil {
+			gotValue = describeExpression(got)
+		}
+
+		if gotValue != test.value {
+			t.Errorf("parsing %q: got value %q, want %q", test.source, gotValue, test.value)
+		}
+	}
+}
+
+func TestParseComputedVar(t *testing.T) {
+	tests := []struct {
+		source    string
+		getter    string
+		hasSetter bool
+		setter    string
+		newValue  string
+	}{
+		{"var Area Integer32 { get { 4 } }", "", false, "", ""},
+		{"var Area Integer32 { get(const& self) { 4 } }", "const& Self", false, "", ""},
+		{"var Area Integer32 {\n    get(const& self) { 4 }\n    set(mut& self, area) { }\n}", "const& Self", true, "mut& Self", "area"},
+		{"var Area Integer32 { get { 4 } set(area) { } }", "", true, "", "area"},
+	}
+
+	for _, test := range tests {
+		def, err := newTestParser(test.source).ParseVariableDefinition()
+
+		if err != nil {
+			t.Errorf("parsing %q: %v", test.source, err)
+			continue
+		}
+
+		computed, ok := def.(ComputedVarDefinitionASTNode)
+
+		if !ok {
+			t.Errorf("parsing %q: got a %T, want a ComputedVarDefinitionASTNode", test.source, def)
+			continue
+		}
+
+		if computed.Name != "Area" || describeType(computed.ValueType) != "Integer32" {
+			t.Errorf("parsing %q: got %s %s, want Area Integer32", test.source, computed.Name, describeType(computed.ValueType))
+		}
+
+		if got := describeSelfType(computed.Getter.SelfType); got != test.getter {
+			t.Errorf("parsing %q: got getter self %q, want %q", test.source, got, test.getter)
+		}
+
+		setter, err := computed.Setter.Value()
+
+		if (err == nil) != test.hasSetter {
+			t.Errorf("parsing %q: got a setter %t, want %t", test.source, err == nil, test.hasSetter)
+			continue
+		}
+
+		if !test.hasSetter {
+			continue
+		}
+
+		if got := describeSelfType(setter.SelfType); got != test.setter || setter.NewValueArgName != test.newValue {
+			t.Errorf("parsing %q: got setter (%q, %s), want (%q, %s)", test.source, got, setter.NewValueArgName, test.setter, test.newValue)
+		}
+	}
+}
+
+// Writes out the self parameter of a getter or setter, or an empty string if it has none.
+func describeSelfType(selfType RefType) string {
+	switch selfType.(type) {
+	case ImmutableReference:
+		return "const& Self"
+	case MutableReference:
+		return "mut& Self"
+	}
+
+	return ""
+}
+
+func TestParseInvalidVariableDefinition(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		check  func(err error) bool
+	}{
+		{"missing type and value", "let x;", isExpectedToken},
+		{"const without a value", "const Limit Integer64;", isExpectedToken},
+		{"missing value after equals", "let x = ;", isUnexpectedToken},
+		{"not a definition", "fn x = 1;", isUnexpectedToken},
+		{"duplicate getter", "var Area Integer32 { get { 4 } get { 5 } }", isDuplicateName},
+		{"duplicate setter", "var Area Integer32 { get { 4 } set(a) { } set(b) { } }", isDuplicateName},
+		{"setter without getter", "var Area Integer32 { set(a) { } }", isUnexpectedToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := newTestParser(test.source).ParseVariableDefinition(); !test.check(err) {
+				t.Errorf("parsing %q: got error %v", test.source, err)
+			}
+		})
+	}
+}
+
+func isUnexpectedToken(err error) bool {
+	var unexpected ParseErrorUnexpectedToken
+
+	return errors.As(err, &unexpected)
+}
+
+func isExpectedToken(err error) bool {
+	var expected ParseErrorExpectedToken
+
+	return errors.As(err, &expected)
+}
+
+func isDuplicateName(err error) bool {
+	var duplicate ParseErrorDuplicateName
+
+	return errors.As(err, &duplicate)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"ljpprojects.org/sqopl/lexer"
+)
+
+type tokenPositionJSON struct {
+	Line   uint32 `json:"line"`
+	Column uint32 `json:"column"`
+	Offset uint64 `json:"offset"`
+}
+
+type tokenJSON struct {
+	Kind     string            `json:"kind"`
+	Spelling string            `json:"spelling"`
+	Start    tokenPositionJSON `json:"start"`
+	End      tokenPositionJSON `json:"end"`
+}
+
+func positionJSON(pos lexer.Position) tokenPositionJSON {
+	return tokenPositionJSON{
+		Line:   pos.Line(),
+		Column: pos.Column(),
+		Offset: pos.Offset(),
+	}
+}
+
+// Prints every token in each file with its kind, spelling and location.
+// Lexing carries on past errors, which are printed to stderr once all tokens have been printed.
+func runTokens(args []string) error {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the tokens of each file as a JSON array")
+
+	sources, err := parseCommandLine(flags, args)
+
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, source := range sources {
+		if len(sources) > 1 && !*asJSON {
+			fmt.Printf("==> %s <==\n", source.Name())
+		}
+
+		errs = append(errs, printTokens(source, *asJSON))
+	}
+
+	return errors.Join(errs...)
+}
+
+func printTokens(source *lexer.Source, asJSON bool) error {
+	options := lexer.DefaultLexerOptions()
+	options.ErrorTokens = true
+
+	lex := lexer.NewLexerWithOptions(source, options)
+	tokens, err := lex.CollectTokens()
+
+	if err != nil {
+		return sourceError{source.Name(), err}
+	}
+
+	if asJSON {
+		out := make([]tokenJSON, 0, len(tokens))
+
+		for _, tk := range tokens {
+			out = append(out, tokenJSON{
+				Kind:     tk.Kind().ToDisplayString(),
+				Spelling: tk.Characters(),
+				Start:    positionJSON(tk.Startpos()),
+				End:      positionJSON(tk.Endpos()),
+			})
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(out); err != nil {
+			return ioError{err}
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+		for _, tk := range tokens {
+			fmt.Fprintf(
+				w,
+				"%d:%d-%d:%d\t%s\t%q\n",
+				tk.Startpos().Line(),
+				tk.Startpos().Column(),
+				tk.Endpos().Line(),
+				tk.Endpos().Column(),
+				tk.Kind().ToDisplayString(),
+				tk.Characters(),
+			)
+		}
+
+		if err := w.Flush(); err != nil {
+			return ioError{err}
+		}
+	}
+
+	for _, warning := range lex.Warnings() {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s\n", source.Name(), warning)
+	}
+
+	var errs []error
+
+	for _, lexErr := range lex.Errors() {
+		errs = append(errs, sourceError{source.Name(), lexErr})
+	}
+
+	return errors.Join(errs...)
+}